@@ -0,0 +1,53 @@
+package retryable
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ADAPTIVE_WINDOW is the number of latencies kept by AdaptiveTimeout and
+// ADAPTIVE_MIN_SAMPLES the number of them needed before it applies.
+const (
+	ADAPTIVE_WINDOW      = 20
+	ADAPTIVE_MIN_SAMPLES = 5
+)
+
+// latencyWindow keeps the latencies of the last successful attempts.
+type latencyWindow struct {
+	mu      sync.Mutex
+	factor  float64
+	samples []time.Duration
+	next    int
+}
+
+func newLatencyWindow(factor float64) *latencyWindow {
+	return &latencyWindow{factor: factor, samples: make([]time.Duration, 0, ADAPTIVE_WINDOW)}
+}
+
+// add records the latency of a successful attempt, replacing the oldest one
+// once the window is full.
+func (w *latencyWindow) add(latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < ADAPTIVE_WINDOW {
+		w.samples = append(w.samples, latency)
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % ADAPTIVE_WINDOW
+}
+
+// timeout returns the 95th percentile of the latencies times the factor, it
+// returns false until there are enough samples.
+func (w *latencyWindow) timeout() (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < ADAPTIVE_MIN_SAMPLES {
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	p95 := sorted[(len(sorted)*95+99)/100-1]
+	return time.Duration(float64(p95) * w.factor), true
+}
@@ -0,0 +1,52 @@
+package retryable
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLatencyWindow(t *testing.T) {
+	w := newLatencyWindow(2)
+	for i := 1; i < ADAPTIVE_MIN_SAMPLES; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+		if _, ok := w.timeout(); ok {
+			t.Fatalf("expected no timeout with %d samples", i)
+		}
+	}
+	w.add(100 * time.Millisecond)
+	if timeout, ok := w.timeout(); !ok || timeout != 200*time.Millisecond {
+		t.Errorf("expected the 95th percentile times 2, got %v (%v)", timeout, ok)
+	}
+
+	for i := 0; i < ADAPTIVE_WINDOW; i++ {
+		w.add(time.Millisecond)
+	}
+	if timeout, _ := w.timeout(); timeout != 2*time.Millisecond {
+		t.Errorf("expected the old latencies to leave the window, got %v", timeout)
+	}
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	latency := time.Millisecond
+	rt := Retry(func() error {
+		time.Sleep(latency)
+		return nil
+	}).SetTimeout(time.Hour).AdaptiveTimeout(3)
+
+	for i := 0; i < ADAPTIVE_MIN_SAMPLES; i++ {
+		if stats := rt.Exec(); stats.Err != nil {
+			t.Fatalf("unexpected error during the warm up: %v", stats.Err)
+		}
+	}
+
+	latency = time.Second
+	start := time.Now()
+	stats := rt.Exec()
+	if !errors.Is(stats.Err, ErrTimeout) {
+		t.Errorf("expected %v once the latencies are known, got %v", ErrTimeout, stats.Err)
+	}
+	if elapsed := time.Since(start); elapsed >= latency {
+		t.Errorf("expected the attempt to time out before %v, got %v", latency, elapsed)
+	}
+}
@@ -0,0 +1,164 @@
+package retryable
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Backoff is the strategy that computes the delay between retries.
+// Delay returns the time to sleep after the given zero based failed attempt
+// and Name identifies the strategy, for example in Describe.
+type Backoff interface {
+	Delay(attempt int) time.Duration
+	Name() string
+}
+
+// DelayRange is the range of the delay after an attempt, Min and Max
+// included. Both are the same for a delay that is not randomized.
+type DelayRange struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// boundedBackoff is a randomized Backoff that knows the range of its delays.
+// The backoffs that do not implement it are deterministic, their range is
+// the delay itself.
+type boundedBackoff interface {
+	Bounds(attempt int) DelayRange
+}
+
+// bounds returns the range of the delays of backoff after the given attempt.
+func bounds(backoff Backoff, attempt int) DelayRange {
+	if bounded, ok := backoff.(boundedBackoff); ok {
+		return bounded.Bounds(attempt)
+	}
+	delay := backoff.Delay(attempt)
+	return DelayRange{Min: delay, Max: delay}
+}
+
+type constantBackoff time.Duration
+
+func (b constantBackoff) Delay(int) time.Duration {
+	return time.Duration(b)
+}
+
+func (b constantBackoff) Name() string {
+	return "constant"
+}
+
+// The function Constant returns a Backoff that always sleeps the same delay,
+// it is the strategy used by SetSleep.
+func Constant(delay time.Duration) Backoff {
+	return constantBackoff(delay)
+}
+
+type exponentialBackoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+func (b exponentialBackoff) Delay(attempt int) time.Duration {
+	delay := float64(b.base) * math.Pow(2, float64(attempt))
+	if b.max > 0 && delay > float64(b.max) {
+		return b.max
+	}
+	if delay > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+func (b exponentialBackoff) Name() string {
+	return "exponential"
+}
+
+// The function Exponential returns a Backoff that doubles the delay after
+// every failed attempt starting from base, base*2^attempt, and never sleeps
+// more than max. A max of zero means there is no maximum.
+func Exponential(base, max time.Duration) Backoff {
+	return exponentialBackoff{base: base, max: max}
+}
+
+// invalidBackoff is a Backoff built with invalid parameters, it makes Exec
+// return its error.
+type invalidBackoff struct {
+	constantBackoff
+	err error
+}
+
+func (b invalidBackoff) Name() string {
+	return "invalid"
+}
+
+func (b invalidBackoff) validate() error {
+	return b.err
+}
+
+// exponentialToMaxBackoff halves the delay from max for every attempt before
+// the one that reaches it, so it reaches max exactly whatever max and
+// atAttempt are.
+type exponentialToMaxBackoff struct {
+	max       time.Duration
+	atAttempt int
+}
+
+func (b exponentialToMaxBackoff) Delay(attempt int) time.Duration {
+	shift := b.atAttempt - 1 - attempt
+	if shift <= 0 {
+		return b.max
+	}
+	if shift >= 63 {
+		return 0
+	}
+	return b.max >> shift
+}
+
+func (b exponentialToMaxBackoff) Name() string {
+	return "exponential"
+}
+
+// The function ExponentialToMax returns an exponential Backoff that doubles
+// the delay after every failed attempt and reaches max at the delay after
+// the failed attempt number atAttempt (starting at 1), staying at max after
+// it: the delay after the failed attempt n is max/2^(atAttempt-n), rounded
+// down. An atAttempt that is not positive makes Exec return
+// ErrInvalidConfig.
+func ExponentialToMax(max time.Duration, atAttempt int) Backoff {
+	if atAttempt <= 0 {
+		return invalidBackoff{err: fmt.Errorf("%w: ExponentialToMax: atAttempt %d is not positive", ErrInvalidConfig, atAttempt)}
+	}
+	return exponentialToMaxBackoff{max: max, atAttempt: atAttempt}
+}
+
+type delaysBackoff []time.Duration
+
+func (b delaysBackoff) Delay(attempt int) time.Duration {
+	if attempt < len(b) {
+		return b[attempt]
+	}
+	return b[len(b)-1]
+}
+
+func (b delaysBackoff) Name() string {
+	return "delays"
+}
+
+type fullJitterBackoff struct {
+	exponentialBackoff
+	random func() float64
+}
+
+func (b fullJitterBackoff) Delay(attempt int) time.Duration {
+	return time.Duration(b.random() * float64(b.exponentialBackoff.Delay(attempt)))
+}
+
+// The Bounds method returns the range of the full jitter delays, from 0 to
+// the exponential delay of the attempt.
+func (b fullJitterBackoff) Bounds(attempt int) DelayRange {
+	return DelayRange{Max: b.exponentialBackoff.Delay(attempt)}
+}
+
+func (b fullJitterBackoff) Name() string {
+	return "full-jitter"
+}
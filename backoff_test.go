@@ -0,0 +1,308 @@
+package retryable
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDelaysAttempts(t *testing.T) {
+	delays := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}
+	var slept []time.Duration
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return errors.New("failed")
+	}).RetryForever().Delays(delays...).
+		WithSleepFunc(func(d time.Duration) { slept = append(slept, d) }).Exec()
+
+	if calls != len(delays) || stats.Attempts != len(delays) {
+		t.Errorf("expected %d attempts, got %d calls and %d attempts", len(delays), calls, stats.Attempts)
+	}
+	if len(slept) != len(delays) {
+		t.Fatalf("expected %d sleeps, got %v", len(delays), slept)
+	}
+	for i, delay := range delays {
+		if slept[i] != delay {
+			t.Errorf("expected the sleep %d to be %v, got %v", i, delay, slept[i])
+		}
+	}
+}
+
+func TestDelaysEmpty(t *testing.T) {
+	calls := 0
+	Retry(func() error {
+		calls++
+		return errors.New("failed")
+	}).SetRetries(5).Delays().Exec()
+
+	if calls != 1 {
+		t.Errorf("expected 1 attempt, got %d", calls)
+	}
+}
+
+// sleeps returns the sleeps between the attempts of rt, whose function always
+// fails.
+func sleeps(rt RetrayableI) []time.Duration {
+	var slept []time.Duration
+	rt.WithSleepFunc(func(d time.Duration) { slept = append(slept, d) }).Exec()
+	return slept
+}
+
+func TestFullJitter(t *testing.T) {
+	failing := func() error { return errors.New("failed") }
+	slept := sleeps(Retry(failing).SetRetries(8).FullJitter(10*time.Millisecond, 100*time.Millisecond).WithSeed(1))
+
+	if len(slept) != 7 {
+		t.Fatalf("expected 7 sleeps, got %v", slept)
+	}
+	distinct := map[time.Duration]bool{}
+	for i, d := range slept {
+		max := 10 * time.Millisecond << i
+		if max > 100*time.Millisecond {
+			max = 100 * time.Millisecond
+		}
+		if d < 0 || d > max {
+			t.Errorf("expected the sleep %d between 0 and %v, got %v", i, max, d)
+		}
+		distinct[d] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("expected randomized sleeps, got %v", slept)
+	}
+
+	again := sleeps(Retry(failing).SetRetries(8).FullJitter(10*time.Millisecond, 100*time.Millisecond).WithSeed(1))
+	for i := range slept {
+		if again[i] != slept[i] {
+			t.Fatalf("expected the same sleeps with the same seed, got %v and %v", slept, again)
+		}
+	}
+}
+
+func TestReplayBackoff(t *testing.T) {
+	replay := []time.Duration{3 * time.Millisecond, time.Millisecond}
+	slept := sleeps(Retry(func() error { return errors.New("failed") }).
+		SetRetries(4).SetSleep(time.Hour).SetJitter(0.5).ReplayBackoff(replay))
+
+	expected := []time.Duration{3 * time.Millisecond, time.Millisecond, time.Millisecond}
+	if len(slept) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, slept)
+	}
+	for i := range expected {
+		if slept[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, slept)
+			break
+		}
+	}
+}
+
+func TestMaxTotalSleep(t *testing.T) {
+	slept := sleeps(Retry(func() error { return errors.New("failed") }).
+		SetRetries(5).SetSleep(4 * time.Millisecond).MaxTotalSleep(10 * time.Millisecond))
+
+	var total time.Duration
+	for _, d := range slept {
+		total += d
+	}
+	if total != 10*time.Millisecond {
+		t.Errorf("expected to sleep 10ms in total, got %v", slept)
+	}
+	if len(slept) > 3 {
+		t.Errorf("expected no sleep once the cap is reached, got %v", slept)
+	}
+}
+
+func TestInitialDelayJitter(t *testing.T) {
+	distinct := map[time.Duration]bool{}
+	for seed := int64(0); seed < 10; seed++ {
+		slept := sleeps(Retry(func() error { return nil }).
+			InitialDelay(10 * time.Millisecond).InitialDelayJitter(0.5).WithSeed(seed))
+		if len(slept) != 1 {
+			t.Fatalf("expected only the initial delay, got %v", slept)
+		}
+		if slept[0] < 5*time.Millisecond || slept[0] > 15*time.Millisecond {
+			t.Errorf("expected the initial delay between 5ms and 15ms, got %v", slept[0])
+		}
+		distinct[slept[0]] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("expected randomized initial delays, got %v", distinct)
+	}
+
+	slept := sleeps(Retry(func() error { return nil }).InitialDelay(10 * time.Millisecond).SetJitter(0.5))
+	if len(slept) != 1 || slept[0] != 10*time.Millisecond {
+		t.Errorf("expected SetJitter not to apply to the initial delay, got %v", slept)
+	}
+}
+
+func TestJitterFunc(t *testing.T) {
+	var attempts []int
+	slept := sleeps(Retry(func() error { return errors.New("failed") }).
+		SetRetries(4).SetSleep(10 * time.Millisecond).WithSeed(1).
+		JitterFunc(func(attempt int) float64 {
+			attempts = append(attempts, attempt)
+			if attempt == 0 {
+				return 0.5
+			}
+			return 0
+		}))
+
+	if len(slept) != 3 {
+		t.Fatalf("expected 3 sleeps, got %v", slept)
+	}
+	if slept[0] < 5*time.Millisecond || slept[0] > 15*time.Millisecond || slept[0] == 10*time.Millisecond {
+		t.Errorf("expected the first sleep jittered by up to 50%%, got %v", slept[0])
+	}
+	if slept[1] != 10*time.Millisecond || slept[2] != 10*time.Millisecond {
+		t.Errorf("expected the later sleeps without jitter, got %v", slept[1:])
+	}
+	if len(attempts) < 3 || attempts[0] != 0 || attempts[len(attempts)-1] != 2 {
+		t.Errorf("expected the zero based failed attempts, got %v", attempts)
+	}
+}
+
+func TestGrowthCap(t *testing.T) {
+	slept := sleeps(Retry(func() error { return errors.New("failed") }).
+		SetRetries(6).SetBackoff(Exponential(time.Millisecond, time.Hour)).GrowthCap(3))
+
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	if len(slept) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, slept)
+	}
+	for i := range expected {
+		if slept[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, slept)
+			break
+		}
+	}
+}
+
+func TestScheduleAround(t *testing.T) {
+	at := time.Now().Add(time.Hour)
+	slept := sleeps(Retry(func() error { return nil }).
+		InitialDelay(time.Minute).ScheduleAround(at, 10*time.Minute).WithSeed(1))
+	if len(slept) != 1 {
+		t.Fatalf("expected only the scheduled delay, got %v", slept)
+	}
+	if slept[0] < 50*time.Minute || slept[0] > time.Hour {
+		t.Errorf("expected a delay in the window before the hour, got %v", slept[0])
+	}
+
+	slept = sleeps(Retry(func() error { return nil }).ScheduleAround(time.Now().Add(-time.Hour), time.Minute))
+	if len(slept) != 0 {
+		t.Errorf("expected no delay once the window passed, got %v", slept)
+	}
+}
+
+func TestExponentialToMax(t *testing.T) {
+	slept := sleeps(Retry(func() error { return errors.New("failed") }).
+		SetRetries(6).SetBackoff(ExponentialToMax(80*time.Millisecond, 3)))
+
+	expected := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond, 80 * time.Millisecond, 80 * time.Millisecond}
+	if len(slept) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, slept)
+	}
+	for i := range expected {
+		if slept[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, slept)
+			break
+		}
+	}
+
+	backoff := ExponentialToMax(100*time.Millisecond, 10)
+	if delay := backoff.Delay(9); delay != 100*time.Millisecond {
+		t.Errorf("expected a non power of two max to be reached exactly, got %v", delay)
+	}
+	if delay := backoff.Delay(8); delay != 50*time.Millisecond {
+		t.Errorf("expected half of the max before it, got %v", delay)
+	}
+
+	backoff = ExponentialToMax(time.Second, 40)
+	if delay := backoff.Delay(39); delay != time.Second {
+		t.Errorf("expected a large atAttempt to reach max, got %v", delay)
+	}
+	if delay := backoff.Delay(30); delay != time.Second>>9 {
+		t.Errorf("expected %v, got %v", time.Second>>9, delay)
+	}
+	if delay := backoff.Delay(0); delay != 0 {
+		t.Errorf("expected the first delay to round down to 0, got %v", delay)
+	}
+
+	stats := Retry(func() error { return nil }).SetBackoff(ExponentialToMax(time.Second, 0)).Exec()
+	if !errors.Is(stats.Err, ErrInvalidConfig) {
+		t.Errorf("expected %v, got %v", ErrInvalidConfig, stats.Err)
+	}
+}
+
+func TestDeadlineTruncatesTheDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		sleep   time.Duration
+		timeout time.Duration
+		min     time.Duration
+		max     time.Duration
+		fits    bool
+	}{
+		{"kept", time.Minute, time.Second, time.Minute, time.Minute, true},
+		{"capped", 2 * time.Hour, 10 * time.Minute, 49 * time.Minute, 50 * time.Minute, true},
+		{"no time left without timeout", 2 * time.Hour, 0, 0, 0, false},
+		{"no time left for the timeout", time.Second, 2 * time.Hour, 0, 0, false},
+	}
+	for _, test := range tests {
+		var slept []time.Duration
+		stats := Retry(func() error { return errors.New("failed") }).
+			SetRetries(2).SetSleep(test.sleep).SetTimeout(test.timeout).Deadline(time.Now().Add(time.Hour)).
+			WithSleepFunc(func(d time.Duration) { slept = append(slept, d) }).Exec()
+
+		if !test.fits {
+			if len(slept) != 0 || !errors.Is(stats.Err, ErrDeadline) || stats.Attempts != 1 {
+				t.Errorf("%s: expected %v without sleeping, got %v after %v", test.name, ErrDeadline, stats.Err, slept)
+			}
+			continue
+		}
+		if len(slept) != 1 || slept[0] < test.min || slept[0] > test.max {
+			t.Errorf("%s: expected a sleep between %v and %v, got %v", test.name, test.min, test.max, slept)
+		}
+		if stats.Attempts != 2 {
+			t.Errorf("%s: expected 2 attempts, got %d", test.name, stats.Attempts)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	failing := func() error { return errors.New("failed") }
+	tests := []struct {
+		name     string
+		rt       RetrayableI
+		expected []DelayRange
+	}{
+		{"FullJitter", Retry(failing).FullJitter(10*time.Millisecond, 30*time.Millisecond), []DelayRange{
+			{0, 10 * time.Millisecond}, {0, 20 * time.Millisecond}, {0, 30 * time.Millisecond}, {0, 30 * time.Millisecond},
+		}},
+		{"SetJitter", Retry(failing).SetBackoff(Exponential(10*time.Millisecond, 0)).SetJitter(0.5), []DelayRange{
+			{5 * time.Millisecond, 15 * time.Millisecond}, {10 * time.Millisecond, 30 * time.Millisecond},
+			{20 * time.Millisecond, 60 * time.Millisecond}, {40 * time.Millisecond, 120 * time.Millisecond},
+		}},
+		{"ReplayBackoff", Retry(failing).SetJitter(0.5).ReplayBackoff([]time.Duration{3 * time.Millisecond, time.Millisecond}), []DelayRange{
+			{3 * time.Millisecond, 3 * time.Millisecond}, {time.Millisecond, time.Millisecond},
+			{time.Millisecond, time.Millisecond}, {time.Millisecond, time.Millisecond},
+		}},
+	}
+	for _, test := range tests {
+		schedule := test.rt.Schedule(len(test.expected))
+		for i, expected := range test.expected {
+			if schedule[i] != expected {
+				t.Errorf("%s: expected %v, got %v", test.name, test.expected, schedule)
+				break
+			}
+		}
+
+		// the delays of a run stay within the schedule
+		slept := sleeps(test.rt.SetRetries(len(test.expected) + 1).WithSeed(1))
+		for i, d := range slept {
+			if d < schedule[i].Min || d > schedule[i].Max {
+				t.Errorf("%s: expected the sleep %d within %v, got %v", test.name, i, schedule[i], d)
+			}
+		}
+	}
+}
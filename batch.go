@@ -0,0 +1,187 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type RetrayableBatchI interface {
+	Configure(fn func(rt RetrayableI)) RetrayableBatchI
+	SetConcurrency(concurrency int) RetrayableBatchI
+	FailFast(failFast bool) RetrayableBatchI
+	LimitInFlight(limiter Limiter) RetrayableBatchI
+	Cancel()
+	Exec() BatchResult
+}
+
+// The Stats field has the Stats of every function, in the same order as the
+// functions of the batch.
+// The Succeeded and Failed fields are the number of functions that succeeded
+// and failed.
+// The Failures field has the indexes of the functions that failed.
+// The Err field joins the errors of the functions that failed, every error is
+// prefixed with the index of its function, or is nil if all succeeded.
+// The AbortedBy field is the index of the function whose non retryable error
+// aborted the batch in FailFast mode, or -1.
+type BatchResult struct {
+	Stats     []Stats
+	Succeeded int
+	Failed    int
+	Failures  []int
+	Err       error
+	AbortedBy int
+}
+
+// Limiter is a semaphore shared by many executions, like the one of
+// LimitInFlight. A *semaphore.Weighted of golang.org/x/sync/semaphore is a
+// Limiter, and ChanLimiter adapts a buffered channel.
+type Limiter interface {
+	Acquire(ctx context.Context, n int64) error
+	Release(n int64)
+}
+
+type chanLimiter chan struct{}
+
+func (l chanLimiter) Acquire(ctx context.Context, n int64) error {
+	for i := int64(0); i < n; i++ {
+		select {
+		case l <- struct{}{}:
+		case <-ctx.Done():
+			l.Release(i)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (l chanLimiter) Release(n int64) {
+	for i := int64(0); i < n; i++ {
+		<-l
+	}
+}
+
+// The function ChanLimiter returns a Limiter backed by the buffered channel
+// ch, its capacity is the number of units.
+func ChanLimiter(ch chan struct{}) Limiter {
+	return chanLimiter(ch)
+}
+
+type RetrayableBatch struct {
+	fns           []func() error
+	configure     func(rt RetrayableI)
+	concurrency   int
+	failFast      bool
+	limiter       Limiter
+	cancelContext context.Context
+	cancelFn      context.CancelFunc
+}
+
+// The Configure method sets a function that receives the RetrayableI of every
+// function of the batch before executing it, so all of them share the same
+// settings. It returns a RetrayableBatchI instance, allowing method chaining.
+func (b *RetrayableBatch) Configure(fn func(rt RetrayableI)) RetrayableBatchI {
+	b.configure = fn
+	return b
+}
+
+// The SetConcurrency method sets the maximum number of functions of the batch
+// executed at the same time, by default all of them are executed at once.
+// It returns a RetrayableBatchI instance, allowing method chaining.
+func (b *RetrayableBatch) SetConcurrency(concurrency int) RetrayableBatchI {
+	b.concurrency = concurrency
+	return b
+}
+
+// The FailFast method sets if the first function that fails with a non
+// retryable error (one with the Aborted outcome, like an error rejected by
+// RetryIf) aborts the batch, cancelling the functions still running and
+// skipping the ones not started. By default all the functions run to
+// completion. It returns a RetrayableBatchI instance, allowing method
+// chaining.
+func (b *RetrayableBatch) FailFast(failFast bool) RetrayableBatchI {
+	b.failFast = failFast
+	return b
+}
+
+// The LimitInFlight method gates the start of every attempt of all the
+// functions of the batch with limiter, so the attempts running at the same
+// time, the timed out ones that keep running in the background included, are
+// bounded across the workers. Every attempt holds one unit of limiter until
+// its goroutine returns. The wait for limiter happens before the attempt
+// starts, so it does not count towards the timeout of the attempt, but it
+// does towards the Deadline and MaxElapsed of the execution. It returns a
+// RetrayableBatchI instance, allowing method chaining.
+func (b *RetrayableBatch) LimitInFlight(limiter Limiter) RetrayableBatchI {
+	b.limiter = limiter
+	return b
+}
+
+// The Cancel method cancels the execution of all the functions of the batch.
+func (b *RetrayableBatch) Cancel() {
+	b.cancelFn()
+}
+
+// The Exec method retries every function of the batch independently and
+// returns a BatchResult with the Stats of each of them and the aggregated
+// result.
+func (b *RetrayableBatch) Exec() BatchResult {
+	result := BatchResult{Stats: make([]Stats, len(b.fns)), AbortedBy: -1}
+	ctx, abort := context.WithCancel(b.cancelContext)
+	defer abort()
+	var mu sync.Mutex
+
+	concurrency := b.concurrency
+	if concurrency <= 0 || concurrency > len(b.fns) {
+		concurrency = len(b.fns)
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, fn := range b.fns {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, fn func() error) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			rt := newRetrayable(ctx, func(context.Context, int) error { return fn() }).requireFunc(fn == nil)
+			rt.limiter = b.limiter
+			if b.configure != nil {
+				b.configure(rt)
+			}
+			stats := rt.Exec()
+			result.Stats[i] = stats
+			if b.failFast && stats.Outcome == OutcomeAborted {
+				mu.Lock()
+				if result.AbortedBy < 0 {
+					result.AbortedBy = i
+					abort()
+				}
+				mu.Unlock()
+			}
+		}(i, fn)
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, stats := range result.Stats {
+		if stats.Err == nil {
+			result.Succeeded++
+			continue
+		}
+		result.Failed++
+		result.Failures = append(result.Failures, i)
+		errs = append(errs, fmt.Errorf("function %d: %w", i, stats.Err))
+	}
+	result.Err = errors.Join(errs...)
+	return result
+}
+
+// The function RetryBatch is creating and returning an instance of the type
+// RetrayableBatchI to retry every function of fns independently.
+func RetryBatch(fns []func() error) RetrayableBatchI {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RetrayableBatch{fns: fns, cancelContext: ctx, cancelFn: cancel}
+}
@@ -0,0 +1,135 @@
+package retryable
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBatch(t *testing.T) {
+	errFailed := errors.New("failed")
+	var inFlight gauge
+	attempts := make([]int, 4)
+	fns := make([]func() error, len(attempts))
+	for i := range fns {
+		i := i
+		fns[i] = func() error {
+			defer inFlight.enter()()
+			time.Sleep(5 * time.Millisecond)
+			attempts[i]++
+			if i%2 == 1 {
+				return errFailed
+			}
+			return nil
+		}
+	}
+
+	result := RetryBatch(fns).
+		Configure(func(rt RetrayableI) { rt.SetRetries(2) }).
+		SetConcurrency(2).
+		Exec()
+
+	if result.Succeeded != 2 || result.Failed != 2 {
+		t.Errorf("expected 2 successes and 2 failures, got %d and %d", result.Succeeded, result.Failed)
+	}
+	if len(result.Failures) != 2 || result.Failures[0] != 1 || result.Failures[1] != 3 {
+		t.Errorf("expected the failures 1 and 3, got %v", result.Failures)
+	}
+	if !errors.Is(result.Err, errFailed) || !strings.Contains(result.Err.Error(), "function 1: failed") || !strings.Contains(result.Err.Error(), "function 3: failed") {
+		t.Errorf("expected the errors prefixed with their index, got %v", result.Err)
+	}
+	for i, stats := range result.Stats {
+		if expected := 1 + i%2; stats.Attempts != expected || attempts[i] != expected {
+			t.Errorf("expected %d attempts of the function %d, got %d", expected, i, stats.Attempts)
+		}
+	}
+	if result.AbortedBy != -1 {
+		t.Errorf("expected no abort, got %d", result.AbortedBy)
+	}
+	if inFlight.peak.Load() > 2 {
+		t.Errorf("expected at most 2 functions at the same time, got %d", inFlight.peak.Load())
+	}
+}
+
+func TestRetryBatchSucceeded(t *testing.T) {
+	result := RetryBatch([]func() error{func() error { return nil }, func() error { return nil }}).Exec()
+	if result.Err != nil || result.Succeeded != 2 || result.Failures != nil {
+		t.Errorf("expected all the functions to succeed, got %v", result.Err)
+	}
+}
+
+func TestRetryBatchFailFast(t *testing.T) {
+	errFatal := errors.New("fatal")
+	release := make(chan struct{})
+	defer close(release)
+	var skipped atomic.Bool
+	blocked := func() error {
+		<-release
+		return nil
+	}
+	fns := []func() error{
+		blocked,
+		func() error {
+			time.Sleep(10 * time.Millisecond)
+			return errFatal
+		},
+		blocked,
+		func() error {
+			skipped.Store(true)
+			return nil
+		},
+	}
+
+	start := time.Now()
+	result := RetryBatch(fns).
+		Configure(func(rt RetrayableI) { rt.SetRetries(3).AbortOn(errFatal) }).
+		SetConcurrency(3).
+		FailFast(true).
+		Exec()
+
+	if result.AbortedBy != 1 {
+		t.Errorf("expected the batch to be aborted by the function 1, got %d", result.AbortedBy)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the running functions to be cancelled, took %v", elapsed)
+	}
+	for _, i := range []int{0, 2} {
+		if !errors.Is(result.Stats[i].Err, ErrCancelled) {
+			t.Errorf("expected the function %d to be cancelled, got %v", i, result.Stats[i].Err)
+		}
+	}
+	if skipped.Load() || !errors.Is(result.Stats[3].Err, ErrAlreadyCancelled) {
+		t.Errorf("expected the function not started to be skipped, got %v", result.Stats[3].Err)
+	}
+}
+
+func TestRetryBatchLimitInFlight(t *testing.T) {
+	var inFlight gauge
+	fns := make([]func() error, 6)
+	for i := range fns {
+		fns[i] = func() error {
+			defer inFlight.enter()()
+			time.Sleep(5 * time.Millisecond)
+			return errors.New("failed")
+		}
+	}
+
+	result := RetryBatch(fns).
+		Configure(func(rt RetrayableI) { rt.SetRetries(3) }).
+		LimitInFlight(ChanLimiter(make(chan struct{}, 2))).
+		Exec()
+
+	if result.Failed != len(fns) {
+		t.Errorf("expected every function to fail, got %d failures", result.Failed)
+	}
+	for i, stats := range result.Stats {
+		if stats.Attempts != 3 {
+			t.Errorf("expected 3 attempts of the function %d, got %d", i, stats.Attempts)
+		}
+	}
+	if inFlight.peak.Load() > 2 {
+		t.Errorf("expected at most 2 attempts in flight across the workers, got %d", inFlight.peak.Load())
+	}
+}
@@ -0,0 +1,88 @@
+package retryable
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a circuit breaker that trips when the failure rate of the last
+// executions reaches a threshold. The outcomes are kept in a ring buffer.
+type breaker struct {
+	mu          sync.Mutex
+	outcomes    []bool
+	next        int
+	count       int
+	failureRate float64
+	cooldown    time.Duration
+	open        bool
+	openedAt    time.Time
+	trial       bool
+}
+
+func newBreaker(window int, failureRate float64, cooldown time.Duration) *breaker {
+	return &breaker{outcomes: make([]bool, window), failureRate: failureRate, cooldown: cooldown}
+}
+
+// allow returns true if an execution can run. Once the cooldown of an open
+// breaker passes, a single trial execution is allowed (half-open).
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// cancel discards a cancelled execution, it does not count in the window.
+// A cancelled trial does not tell if the service recovered, so the breaker
+// stays open and allows a new trial.
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// record adds the result of an execution. The result of the trial execution
+// closes the breaker, starting with an empty window, or opens it again.
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		if !b.trial {
+			return
+		}
+		b.trial = false
+		if failed {
+			b.openedAt = time.Now()
+			return
+		}
+		b.open = false
+		b.next, b.count = 0, 0
+		return
+	}
+
+	b.outcomes[b.next] = failed
+	b.next = (b.next + 1) % len(b.outcomes)
+	if b.count < len(b.outcomes) {
+		b.count++
+	}
+	if b.count < len(b.outcomes) {
+		return
+	}
+
+	failures := 0
+	for _, failed := range b.outcomes {
+		if failed {
+			failures++
+		}
+	}
+	if float64(failures)/float64(len(b.outcomes)) >= b.failureRate {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
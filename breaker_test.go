@@ -0,0 +1,124 @@
+package retryable
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	var fails atomic.Bool
+	fails.Store(true)
+	calls := 0
+	rt := Retry(func() error {
+		calls++
+		if fails.Load() {
+			return errors.New("failed")
+		}
+		return nil
+	}).CircuitBreakerWindow(4, 0.5, 20*time.Millisecond)
+
+	// 2 failures out of the window of 4 trip the breaker
+	fails.Store(false)
+	rt.Exec()
+	rt.Exec()
+	fails.Store(true)
+	rt.Exec()
+	if stats := rt.Exec(); errors.Is(stats.Err, ErrCircuitOpen) {
+		t.Fatal("expected the breaker to be closed until the window is full")
+	}
+	stats := rt.Exec()
+	if !errors.Is(stats.Err, ErrCircuitOpen) || stats.Outcome != OutcomeCircuitOpen || stats.Skipped != 1 {
+		t.Fatalf("expected %v, got %v (%v)", ErrCircuitOpen, stats.Err, stats.Outcome)
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+
+	// the failed trial opens it again, the successful one closes it
+	time.Sleep(30 * time.Millisecond)
+	if stats = rt.Exec(); errors.Is(stats.Err, ErrCircuitOpen) {
+		t.Fatal("expected a trial after the cooldown")
+	}
+	if stats = rt.Exec(); !errors.Is(stats.Err, ErrCircuitOpen) {
+		t.Fatalf("expected the failed trial to open the breaker, got %v", stats.Err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	fails.Store(false)
+	rt.Exec()
+	if stats = rt.Exec(); stats.Err != nil {
+		t.Errorf("expected the successful trial to close the breaker, got %v", stats.Err)
+	}
+}
+
+func TestCircuitBreakerIgnoresCancelled(t *testing.T) {
+	var stop atomic.Bool
+	failing := func() error { return errors.New("failed") }
+	cancelled := func(rt RetrayableI) {
+		stop.Store(true)
+		if stats := rt.Exec(); stats.Outcome != OutcomeCancelled {
+			t.Fatalf("expected a cancelled execution, got %v", stats.Outcome)
+		}
+		stop.Store(false)
+	}
+
+	rt := Retry(failing).StopWhenFlag(&stop).CircuitBreakerWindow(2, 1, time.Hour)
+	rt.Exec()
+	cancelled(rt)
+	rt.Exec()
+	if stats := rt.Exec(); !errors.Is(stats.Err, ErrCircuitOpen) {
+		t.Errorf("expected the cancelled execution not to dilute the window, got %v", stats.Err)
+	}
+
+	rt = Retry(failing).StopWhenFlag(&stop).CircuitBreakerWindow(2, 1, 20*time.Millisecond)
+	rt.Exec()
+	rt.Exec()
+	time.Sleep(30 * time.Millisecond)
+	cancelled(rt)
+	if stats := rt.Exec(); errors.Is(stats.Err, ErrCircuitOpen) {
+		t.Fatal("expected a new trial after the cancelled one")
+	}
+	if stats := rt.Exec(); !errors.Is(stats.Err, ErrCircuitOpen) {
+		t.Errorf("expected the cancelled trial to leave the breaker open, got %v", stats.Err)
+	}
+}
+
+func TestCircuitBreakerPanicCountsAsFailure(t *testing.T) {
+	var panics atomic.Int32
+	var fails atomic.Bool
+	panics.Store(2)
+	fails.Store(true)
+	rt := Retry(func() error {
+		if fails.Load() {
+			return errors.New("failed")
+		}
+		return nil
+	}).SetRetries(2).SetBackoff(panicBackoff{panics: &panics}).
+		CircuitBreakerWindow(1, 0.5, 10*time.Millisecond)
+
+	stats := rt.Exec()
+	if !errors.Is(stats.Err, ErrPanic) {
+		t.Fatalf("expected %v, got %v", ErrPanic, stats.Err)
+	}
+	if stats.Attempts != 1 {
+		t.Errorf("expected the attempt before the panic, got %d", stats.Attempts)
+	}
+	if stats = rt.Exec(); !errors.Is(stats.Err, ErrCircuitOpen) {
+		t.Fatalf("expected the panic to open the breaker, got %v", stats.Err)
+	}
+
+	// the half open trial panics too and must not stay pending
+	time.Sleep(20 * time.Millisecond)
+	if stats = rt.Exec(); !errors.Is(stats.Err, ErrPanic) {
+		t.Fatalf("expected %v, got %v", ErrPanic, stats.Err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	fails.Store(false)
+	if stats = rt.Exec(); stats.Err != nil {
+		t.Fatalf("expected the trial to run and succeed, got %v", stats.Err)
+	}
+	if lifetime := rt.Lifetime(); lifetime.Attempts != 3 {
+		t.Errorf("expected 3 lifetime attempts, got %d", lifetime.Attempts)
+	}
+}
@@ -0,0 +1,78 @@
+package retryable
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Controller lets a function executed with RetryControlled adjust the next
+// attempt. SetNextTimeout overrides the timeout of the next attempt and
+// SetNextDelay overrides the delay before it, both apply only once and then
+// the configured settings are used again.
+type Controller interface {
+	SetNextTimeout(timeout time.Duration)
+	SetNextDelay(delay time.Duration)
+}
+
+type controller struct {
+	mu         sync.Mutex
+	timeout    time.Duration
+	hasTimeout bool
+	delay      time.Duration
+	hasDelay   bool
+}
+
+func (c *controller) SetNextTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	c.timeout, c.hasTimeout = timeout, true
+	c.mu.Unlock()
+}
+
+func (c *controller) SetNextDelay(delay time.Duration) {
+	c.mu.Lock()
+	c.delay, c.hasDelay = delay, true
+	c.mu.Unlock()
+}
+
+// takeTimeout returns and clears the timeout override, if any.
+func (c *controller) takeTimeout() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timeout, ok := c.timeout, c.hasTimeout
+	c.hasTimeout = false
+	return timeout, ok
+}
+
+// peekTimeout returns the timeout override, if any, without clearing it.
+func (c *controller) peekTimeout() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.timeout, c.hasTimeout
+}
+
+// takeDelay returns and clears the delay override, if any.
+func (c *controller) takeDelay() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delay, ok := c.delay, c.hasDelay
+	c.hasDelay = false
+	return delay, ok
+}
+
+// reset clears the overrides left by a previous execution.
+func (c *controller) reset() {
+	c.mu.Lock()
+	c.hasTimeout, c.hasDelay = false, false
+	c.mu.Unlock()
+}
+
+// The function RetryControlled is like RetryContext, but fn also receives a
+// Controller to adjust the timeout and the delay of the next attempt, for
+// example to increase the timeout when the function knows the next attempt
+// will be slower.
+func RetryControlled(ctx context.Context, fn func(ctx context.Context, c Controller) error) RetrayableI {
+	var r *Retrayable
+	r = newRetrayable(ctx, func(ctx context.Context, _ int) error { return fn(ctx, &r.control) })
+	return r.requireFunc(fn == nil)
+}
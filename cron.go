@@ -0,0 +1,211 @@
+package retryable
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CRON_HORIZON is how far in the future the next time of a cron schedule is
+// looked for, a spec that matches no time within it, like "0 0 30 2 *", is
+// invalid.
+const CRON_HORIZON = 5 * 365 * 24 * time.Hour
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonths = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+var cronDays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// cronSchedule is a parsed cron spec, every field is the set of its matching
+// values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+// parseCron parses a standard cron spec of 5 fields, or one of its
+// descriptors like "@hourly".
+func parseCron(spec string) (cronSchedule, error) {
+	if descriptor, ok := cronDescriptors[strings.ToLower(strings.TrimSpace(spec))]; ok {
+		spec = descriptor
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("expected 5 fields, found %d", len(fields))
+	}
+
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return cronSchedule{}, fmt.Errorf("minute: %v", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return cronSchedule{}, fmt.Errorf("hour: %v", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return cronSchedule{}, fmt.Errorf("day of month: %v", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return cronSchedule{}, fmt.Errorf("month: %v", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDays); err != nil {
+		return cronSchedule{}, fmt.Errorf("day of week: %v", err)
+	}
+	// 7 is also Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	// like in vixie cron a field starting with a star, like "*/2", does not
+	// restrict the day
+	s.anyDom, s.anyDow = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps,
+// like "*/15" or "1-5,10", between min and max. names are the names of the
+// values starting at 0, if any.
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			expr, step = part[:i], n
+		}
+
+		from, to := min, max
+		switch {
+		case expr == "*":
+		case strings.Contains(expr, "-"):
+			bounds := strings.SplitN(expr, "-", 2)
+			var err error
+			if from, err = cronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if to, err = cronValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := cronValue(expr, names)
+			if err != nil {
+				return 0, err
+			}
+			from = value
+			if step == 1 {
+				to = value
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for value := from; value <= to; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// cronValue parses a number or one of names.
+func cronValue(value string, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(value, name) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return n, nil
+}
+
+// next returns the first time after t that matches the schedule, or the zero
+// time if there is none within CRON_HORIZON.
+func (s cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(CRON_HORIZON)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case s.month&(1<<month) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay returns true if the day of t matches the schedule, when both the
+// day of month and the day of week are restricted either of them matches.
+func (s cronSchedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+type cronBackoff struct {
+	schedule cronSchedule
+	now      func() time.Time
+}
+
+func (b cronBackoff) Delay(int) time.Duration {
+	now := b.now()
+	next := b.schedule.next(now)
+	if next.IsZero() {
+		return CRON_HORIZON
+	}
+	return next.Sub(now)
+}
+
+func (b cronBackoff) Name() string {
+	return "cron"
+}
+
+// The CronSchedule method schedules the retries at the times that match the
+// cron spec instead of after a delay, for long lived pollers that run on a
+// schedule and stop on success. The spec has the 5 standard fields, minute,
+// hour, day of month, month and day of week, with lists, ranges, steps and
+// the names of the months and days, like "*/15 9-17 * * MON-FRI", or is one
+// of the descriptors @yearly, @monthly, @weekly, @daily and @hourly. Every
+// retry waits until the next matching minute in the location of the clock
+// (see WithClock), the first attempt runs right away.
+//
+// It replaces the value of SetSleep and SetBackoff, and a later call to them
+// replaces it. An invalid spec, or one that never matches, makes Exec return
+// ErrInvalidConfig. It returns a RetrayableI instance, allowing method
+// chaining.
+func (r *Retrayable) CronSchedule(spec string) RetrayableI {
+	schedule, err := parseCron(spec)
+	if err == nil && schedule.next(r.clock()).IsZero() {
+		err = fmt.Errorf("%q never matches", spec)
+	}
+	if err != nil {
+		r.backoff = invalidBackoff{err: fmt.Errorf("%w: CronSchedule: %v", ErrInvalidConfig, err)}
+		return r
+	}
+	r.backoff = cronBackoff{schedule: schedule, now: r.clock}
+	return r
+}
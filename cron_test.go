@@ -0,0 +1,68 @@
+package retryable
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, time.October, 14, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, time.October, 14, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * MON-FRI", time.Date(2026, time.October, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 JAN *", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		// both days restricted, either of them matches
+		{"0 0 20 * FRI", time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)},
+		// a field starting with a star does not restrict the day
+		{"0 0 */2 * FRI", time.Date(2026, time.October, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 20 * */2", time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		schedule, err := parseCron(test.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.spec, err)
+			continue
+		}
+		if next := schedule.next(now); !next.Equal(test.next) {
+			t.Errorf("%q: expected %v, got %v", test.spec, test.next, next)
+		}
+	}
+}
+
+func TestCronScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "0 0 30 2 *", "0 0 * * FOO"} {
+		stats := Retry(func() error { return nil }).CronSchedule(spec).Exec()
+		if !errors.Is(stats.Err, ErrInvalidConfig) {
+			t.Errorf("%q: expected %v, got %v", spec, ErrInvalidConfig, stats.Err)
+		}
+	}
+}
+
+func TestCronScheduleWaitsForTheNextMatch(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 10, 7, 30, 0, time.UTC)
+	var slept []time.Duration
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("failed")
+		}
+		return nil
+	}).SetRetries(3).WithClock(func() time.Time { return now }).CronSchedule("*/15 * * * *").
+		WithSleepFunc(func(d time.Duration) { slept = append(slept, d) }).Exec()
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	expected := 7*time.Minute + 30*time.Second
+	if len(slept) != 2 || slept[0] != expected || slept[1] != expected {
+		t.Errorf("expected 2 sleeps of %v, got %v", expected, slept)
+	}
+}
@@ -0,0 +1,35 @@
+package retryable
+
+import "context"
+
+// freshKey is the context key of the signal of ForceFreshEachRetry.
+type freshKey struct{}
+
+// The ForceFreshEachRetry method sets if the context of every retry, not the
+// one of the first attempt, signals the function to use a fresh connection
+// instead of the pooled one that may have caused the failure. The function
+// reads the signal with FreshFromContext from the context it receives, so it
+// is only available to the functions of RetryContext and the other
+// constructors that receive one. The attempts of ParallelQuorum are not
+// retries and never get it. By default it is disabled. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) ForceFreshEachRetry(fresh bool) RetrayableI {
+	r.forceFresh = fresh
+	return r
+}
+
+// The function FreshFromContext returns true if the attempt that received ctx
+// must use a fresh connection, see ForceFreshEachRetry.
+func FreshFromContext(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshKey{}).(bool)
+	return fresh
+}
+
+// attemptContext returns the context of the attempt, cancelled with the
+// returned function once the attempt is over.
+func (r *Retrayable) attemptContext(ctx context.Context, attempt int) (context.Context, context.CancelFunc) {
+	if r.forceFresh && attempt > 0 {
+		ctx = context.WithValue(ctx, freshKey{}, true)
+	}
+	return context.WithCancel(ctx)
+}
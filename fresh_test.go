@@ -0,0 +1,29 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestForceFreshEachRetry(t *testing.T) {
+	var fresh []bool
+	record := func(ctx context.Context) error {
+		fresh = append(fresh, FreshFromContext(ctx))
+		return errors.New("failed")
+	}
+
+	RetryContext(context.Background(), record).SetRetries(3).ForceFreshEachRetry(true).Exec()
+	if len(fresh) != 3 || fresh[0] || !fresh[1] || !fresh[2] {
+		t.Errorf("expected the signal on the retries but not the first attempt, got %v", fresh)
+	}
+
+	fresh = nil
+	RetryContext(context.Background(), record).SetRetries(3).Exec()
+	for _, f := range fresh {
+		if f {
+			t.Errorf("expected no signal by default, got %v", fresh)
+			break
+		}
+	}
+}
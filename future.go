@@ -0,0 +1,37 @@
+package retryable
+
+// Future is the result of an execution running in the background, like the
+// one of ExecFuture. It is safe to use it from several goroutines.
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+}
+
+// newFuture runs fn in a new goroutine and returns the Future of its result.
+func newFuture[T any](fn func() T) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	go func() {
+		f.value = fn()
+		close(f.done)
+	}()
+	return f
+}
+
+// The Await method blocks until the execution finishes and returns its
+// result, it can be called any number of times.
+func (f *Future[T]) Await() T {
+	<-f.done
+	return f.value
+}
+
+// The Poll method returns the result and true if the execution finished,
+// or the zero value of T and false without blocking if it is still running.
+func (f *Future[T]) Poll() (T, bool) {
+	select {
+	case <-f.done:
+		return f.value, true
+	default:
+		var zero T
+		return zero, false
+	}
+}
@@ -0,0 +1,89 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFutureAwaitAndPoll(t *testing.T) {
+	release := make(chan struct{})
+	future := Retry(func() error {
+		<-release
+		return nil
+	}).ExecFuture()
+
+	if stats, ok := future.Poll(); ok || stats.Attempts != 0 {
+		t.Fatalf("expected the execution to be running, got %v after %d attempts", ok, stats.Attempts)
+	}
+	close(release)
+
+	stats := future.Await()
+	if stats.Err != nil || stats.Attempts != 1 {
+		t.Errorf("expected a success in 1 attempt, got %v after %d attempts", stats.Err, stats.Attempts)
+	}
+	if again := future.Await(); again.Attempts != stats.Attempts {
+		t.Errorf("expected Await to return the same stats, got %d attempts", again.Attempts)
+	}
+	if polled, ok := future.Poll(); !ok || polled.Attempts != stats.Attempts {
+		t.Errorf("expected Poll to return the stats once finished, got %v", ok)
+	}
+}
+
+func TestFutureCancel(t *testing.T) {
+	started := make(chan struct{})
+	rt := RetryContext(context.Background(), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	future := rt.ExecFuture()
+	<-started
+	rt.Cancel()
+
+	if stats := future.Await(); !errors.Is(stats.Err, ErrCancelled) {
+		t.Errorf("expected %v, got %v", ErrCancelled, stats.Err)
+	}
+}
+
+func TestDoneAfterExecAsync(t *testing.T) {
+	release := make(chan struct{})
+	var finalized atomic.Bool
+	rt := Retry(func() error {
+		<-release
+		return nil
+	}).Finalize(func(*Stats) { finalized.Store(true) })
+
+	select {
+	case <-rt.Done():
+		t.Fatal("expected Done not to be closed before the first execution")
+	default:
+	}
+
+	results := rt.ExecAsync()
+	done := rt.Done()
+	select {
+	case <-done:
+		t.Fatal("expected Done not to be closed while running")
+	default:
+	}
+	close(release)
+
+	select {
+	case <-done:
+		if !finalized.Load() {
+			t.Error("expected Done to be closed after Finalize")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Done to be closed once Exec returns")
+	}
+	if stats := <-results; stats.Err != nil {
+		t.Errorf("unexpected error: %v", stats.Err)
+	}
+
+	next := rt.ExecAsync()
+	<-rt.Done()
+	<-next
+}
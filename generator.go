@@ -0,0 +1,82 @@
+package retryable
+
+import "context"
+
+type streamPage[T any] struct {
+	value T
+	more  bool
+}
+
+// RetrayableStream retries every result of a generator function, like the
+// pages of a paginated fetch, where each of them can fail and be retried on
+// its own. It embeds RetrayableI, so every setting is available, and every
+// result is a separate execution with those settings.
+type RetrayableStream[T any] struct {
+	RetrayableI
+	ctx   context.Context
+	pages *RetrayableValue[streamPage[T]]
+}
+
+// The function RetryStream is creating and returning an instance of the type
+// RetrayableStream. The function fn returns the next result, whether there
+// are more results after it, and an error. A failed call is retried until it
+// succeeds or the retries are exhausted, and fn is called again for the next
+// result until it reports there are no more.
+func RetryStream[T any](fn func() (T, bool, error)) *RetrayableStream[T] {
+	pages := RetryValue(func() (streamPage[T], error) {
+		value, more, err := fn()
+		return streamPage[T]{value: value, more: more}, err
+	})
+	rt := pages.RetrayableI.(*Retrayable).requireFunc(fn == nil)
+	return &RetrayableStream[T]{RetrayableI: rt, ctx: rt.cancelContext, pages: pages}
+}
+
+// The Configure method calls fn with the RetrayableI of the instance to change
+// its settings and returns the typed instance, so the chain can end with its
+// typed Exec.
+func (r *RetrayableStream[T]) Configure(fn func(rt RetrayableI)) *RetrayableStream[T] {
+	fn(r.RetrayableI)
+	return r
+}
+
+// The Exec method sends every successful result to out, in order, until fn
+// reports there are no more results or a result fails all its retries, and
+// closes out before returning. The returned Stats aggregate the executions of
+// all the results: the attempts, retries and timeouts are added up,
+// Successes is the number of results sent, and Err and Outcome are the ones
+// of the last execution.
+//
+// Exec blocks on out until the result is received, so a slow consumer slows
+// down the generator (use a buffered channel to allow the generator to run
+// ahead of it). Cancel also stops the wait on out.
+func (r *RetrayableStream[T]) Exec(out chan<- T) Stats {
+	defer close(out)
+	var total Stats
+	for {
+		page := r.pages.Exec()
+		total.Attempts += page.Attempts
+		total.Retries += page.Retries
+		total.Timeout += page.Timeout
+		total.TimeoutDuration += page.TimeoutDuration
+		total.Skipped += page.Skipped
+		total.Err = page.Err
+		total.Outcome = page.Outcome
+		total.Fields = page.Fields
+		total.UsedFallback = total.UsedFallback || page.UsedFallback
+		if page.Err != nil {
+			return total
+		}
+
+		select {
+		case out <- page.Value.value:
+			total.Successes++
+		case <-r.ctx.Done():
+			total.Err = causeError{err: ErrCancelled, cause: r.ctx.Err()}
+			total.Outcome = OutcomeCancelled
+			return total
+		}
+		if !page.Value.more {
+			return total
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package retryable
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryStream(t *testing.T) {
+	// every page fails once before succeeding, there are 3 pages
+	calls := 0
+	out := make(chan int, 3)
+	stats := RetryStream(func() (int, bool, error) {
+		calls++
+		if calls%2 == 1 {
+			return 0, false, errors.New("failed")
+		}
+		page := calls / 2
+		return page, page < 3, nil
+	}).Configure(func(rt RetrayableI) { rt.SetRetries(3) }).Exec(out)
+
+	if stats.Err != nil || stats.Outcome != OutcomeSuccess {
+		t.Fatalf("unexpected error: %v (%v)", stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 6 || stats.Retries != 3 || stats.Successes != 3 {
+		t.Errorf("expected 6 attempts, 3 retries and 3 successes, got %d, %d and %d", stats.Attempts, stats.Retries, stats.Successes)
+	}
+	var pages []int
+	for page := range out {
+		pages = append(pages, page)
+	}
+	if len(pages) != 3 || pages[0] != 1 || pages[2] != 3 {
+		t.Errorf("expected the pages 1 to 3 in order, got %v", pages)
+	}
+}
+
+func TestRetryStreamPageFails(t *testing.T) {
+	errFailed := errors.New("failed")
+	calls := 0
+	out := make(chan string, 2)
+	stats := RetryStream(func() (string, bool, error) {
+		calls++
+		if calls == 1 {
+			return "first", true, nil
+		}
+		return "", true, errFailed
+	}).Configure(func(rt RetrayableI) { rt.SetRetries(2) }).Exec(out)
+
+	if !errors.Is(stats.Err, errFailed) || stats.Outcome != OutcomeExhausted {
+		t.Errorf("expected %v, got %v (%v)", errFailed, stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 3 || stats.Successes != 1 {
+		t.Errorf("expected 3 attempts and 1 success, got %d and %d", stats.Attempts, stats.Successes)
+	}
+	if page, ok := <-out; !ok || page != "first" {
+		t.Errorf("expected the first page, got %q", page)
+	}
+	if _, ok := <-out; ok {
+		t.Error("expected out to be closed")
+	}
+}
+
+func TestRetryStreamCancelWhileBlocked(t *testing.T) {
+	rs := RetryStream(func() (int, bool, error) { return 1, true, nil })
+	out := make(chan int)
+	time.AfterFunc(20*time.Millisecond, rs.Cancel)
+
+	stats := rs.Exec(out)
+	if !errors.Is(stats.Err, ErrCancelled) || stats.Outcome != OutcomeCancelled {
+		t.Errorf("expected %v, got %v (%v)", ErrCancelled, stats.Err, stats.Outcome)
+	}
+	if _, ok := <-out; ok {
+		t.Error("expected out to be closed")
+	}
+}
@@ -0,0 +1,84 @@
+package retryable
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Group creates RetrayableI instances that share the same parent context,
+// cancelling the group cancels the execution of all of them, for example
+// to abort every running retry on shutdown:
+//
+//	g := retryable.NewGroup(ctx)
+//	go g.Retry(PollApi).SetRetries(10).Exec()
+//	go g.Retry(PollDb).SetRetries(10).Exec()
+//
+//	g.Cancel() // every Exec returns ErrCancelled
+type Group struct {
+	cancelContext context.Context
+	cancelFn      context.CancelFunc
+	limited       atomic.Bool
+	budget        atomic.Int64
+}
+
+// The Retry method is creating and returning an instance of the type
+// RetrayableI that belongs to the group.
+func (g *Group) Retry(fn func() error) RetrayableI {
+	rt := newRetrayable(g.cancelContext, func(context.Context, int) error { return fn() }).requireFunc(fn == nil)
+	rt.group = g
+	return rt
+}
+
+// The RetryContext method is like RetryContext, the context received by fn
+// is also done when the group is cancelled.
+func (g *Group) RetryContext(fn func(ctx context.Context) error) RetrayableI {
+	rt := newRetrayable(g.cancelContext, func(ctx context.Context, _ int) error { return fn(ctx) }).requireFunc(fn == nil)
+	rt.group = g
+	return rt
+}
+
+// The SetBudget method sets the total number of attempts shared by all the
+// instances of the group, for example to cap the load of the retries during
+// an incident. Every attempt of any instance takes one from the budget, once
+// it is exhausted the attempts are refused and Exec returns
+// ErrGroupBudgetExhausted with the Exhausted outcome. An instance that
+// already executed some attempts reports them in its Stats, and its error
+// also wraps the error of its last attempt. It is safe to call it while the
+// instances are running. By default the budget is unlimited. It returns the
+// Group, allowing method chaining.
+func (g *Group) SetBudget(attempts int64) *Group {
+	g.budget.Store(attempts)
+	g.limited.Store(true)
+	return g
+}
+
+// The Remaining method returns the attempts left in the budget of the group,
+// or -1 when it is unlimited.
+func (g *Group) Remaining() int64 {
+	if !g.limited.Load() {
+		return -1
+	}
+	if left := g.budget.Load(); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// take takes an attempt from the budget, it returns false if it is
+// exhausted.
+func (g *Group) take() bool {
+	return !g.limited.Load() || g.budget.Add(-1) >= 0
+}
+
+// The Cancel method cancels the execution of all the instances of the group,
+// the ones created after it can not be executed.
+func (g *Group) Cancel() {
+	g.cancelFn()
+}
+
+// The function NewGroup is creating and returning a Group, it is also
+// cancelled when ctx is done.
+func NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancelContext: ctx, cancelFn: cancel}
+}
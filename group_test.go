@@ -0,0 +1,83 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupCancelsEveryExecution(t *testing.T) {
+	g := NewGroup(context.Background())
+	results := make(chan Stats, 2)
+	go func() {
+		results <- g.Retry(func() error { return errors.New("failed") }).SetRetries(3).SetSleep(time.Hour).Exec()
+	}()
+	go func() {
+		results <- g.RetryContext(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).Exec()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	g.Cancel()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case stats := <-results:
+			if !errors.Is(stats.Err, ErrCancelled) {
+				t.Errorf("expected %v, got %v", ErrCancelled, stats.Err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the executions to be cancelled")
+		}
+	}
+
+	stats := g.Retry(func() error { return nil }).Exec()
+	if !errors.Is(stats.Err, ErrAlreadyCancelled) {
+		t.Errorf("expected %v for a new instance, got %v", ErrAlreadyCancelled, stats.Err)
+	}
+}
+
+func TestGroupCancelledWithItsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewGroup(ctx)
+	cancel()
+
+	stats := g.Retry(func() error { return nil }).Exec()
+	if !errors.Is(stats.Err, ErrAlreadyCancelled) {
+		t.Errorf("expected %v, got %v", ErrAlreadyCancelled, stats.Err)
+	}
+}
+
+func TestGroupBudget(t *testing.T) {
+	errFailed := errors.New("failed")
+	g := NewGroup(context.Background()).SetBudget(5)
+	if g.Remaining() != 5 {
+		t.Fatalf("expected 5 attempts left, got %d", g.Remaining())
+	}
+
+	first := g.Retry(func() error { return errFailed }).SetRetries(3).Exec()
+	if !errors.Is(first.Err, errFailed) || first.Attempts != 3 || g.Remaining() != 2 {
+		t.Fatalf("expected the first instance to run its 3 attempts, got %d and %d left", first.Attempts, g.Remaining())
+	}
+
+	second := g.Retry(func() error { return errFailed }).SetRetries(3).Exec()
+	if !errors.Is(second.Err, ErrGroupBudgetExhausted) || !errors.Is(second.Err, errFailed) {
+		t.Errorf("expected %v wrapping the last error, got %v", ErrGroupBudgetExhausted, second.Err)
+	}
+	if second.Outcome != OutcomeExhausted || second.Attempts != 2 {
+		t.Errorf("expected the exhausted outcome after the 2 attempts left, got %v after %d", second.Outcome, second.Attempts)
+	}
+	if g.Remaining() != 0 {
+		t.Errorf("expected no attempt left, got %d", g.Remaining())
+	}
+
+	third := g.Retry(func() error { return nil }).Exec()
+	if !errors.Is(third.Err, ErrGroupBudgetExhausted) || third.Attempts != 0 {
+		t.Errorf("expected %v without attempts, got %v after %d", ErrGroupBudgetExhausted, third.Err, third.Attempts)
+	}
+	if NewGroup(context.Background()).Remaining() != -1 {
+		t.Error("expected an unlimited budget by default")
+	}
+}
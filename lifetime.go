@@ -0,0 +1,42 @@
+package retryable
+
+import "sync/atomic"
+
+// LifetimeStats are the cumulative stats of all the Exec calls of an
+// instance, unlike Stats that describe a single execution.
+// The Runs field is the number of Exec calls that finished.
+// The Successes field is the number of them that succeeded.
+// The Attempts field is the total number of attempts of the function.
+type LifetimeStats struct {
+	Runs      int64
+	Successes int64
+	Attempts  int64
+}
+
+type lifetime struct {
+	runs      atomic.Int64
+	successes atomic.Int64
+	attempts  atomic.Int64
+}
+
+// record adds a finished execution to the counters.
+func (l *lifetime) record(stats Stats) {
+	l.runs.Add(1)
+	if stats.Err == nil {
+		l.successes.Add(1)
+	}
+}
+
+func (l *lifetime) snapshot() LifetimeStats {
+	return LifetimeStats{
+		Runs:      l.runs.Load(),
+		Successes: l.successes.Load(),
+		Attempts:  l.attempts.Load(),
+	}
+}
+
+func (l *lifetime) reset() {
+	l.runs.Store(0)
+	l.successes.Store(0)
+	l.attempts.Store(0)
+}
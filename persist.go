@@ -0,0 +1,44 @@
+package retryable
+
+import "time"
+
+// State is the failure state of the executions of an instance, saved by
+// PersistentState to continue the backoff across process restarts. The
+// ConsecutiveFailures field is the number of failed attempts since the last
+// successful execution, and the LastBackoff field is the delay of the
+// backoff that follows the last of them, without jitter, the largest one for
+// a randomized backoff like FullJitter.
+type State struct {
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	LastBackoff         time.Duration `json:"last_backoff"`
+}
+
+// The PersistentState method sets the functions that load and save the State
+// of the instance, for example from a file, so consecutive invocations of a
+// command line tool against a service that is down do not all start from
+// zero backoff. Exec calls load before the first attempt, waits the
+// LastBackoff before it and continues the backoff from the
+// ConsecutiveFailures, and calls save with the updated State once it
+// finishes, the zero State after a successful execution. The executions that
+// run no attempt do not save anything.
+//
+// load and save are called from the goroutine of Exec, once per execution,
+// they must synchronize the access to the stored State themselves if several
+// executions, or processes, share it. It returns a RetrayableI instance,
+// allowing method chaining.
+func (r *Retrayable) PersistentState(load func() State, save func(State)) RetrayableI {
+	r.loadState = load
+	r.saveState = save
+	return r
+}
+
+// nextState returns the State to save after an execution that continued
+// prior. It computes the delay without side effects, no delay of the
+// Controller and no random number is consumed, and ReplayBackoff is ignored.
+func (r *Retrayable) nextState(prior State, stats Stats) State {
+	if stats.Err == nil && !stats.UsedFallback {
+		return State{}
+	}
+	failures := prior.ConsecutiveFailures + stats.Attempts - stats.Successes
+	return State{ConsecutiveFailures: failures, LastBackoff: bounds(r.backoff, r.growthAttempt(failures-1)).Max}
+}
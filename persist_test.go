@@ -0,0 +1,75 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// saveTo returns the load and save functions of PersistentState over state.
+func saveTo(state *State) (func() State, func(State)) {
+	return func() State { return *state }, func(s State) { *state = s }
+}
+
+func TestPersistentStateContinuesTheBackoff(t *testing.T) {
+	var state State
+	load, save := saveTo(&state)
+	rt := Retry(func() error { return errors.New("failed") }).
+		SetRetries(2).SetBackoff(Exponential(time.Millisecond, time.Second)).PersistentState(load, save)
+
+	rt.Exec()
+	if state.ConsecutiveFailures != 2 || state.LastBackoff != 2*time.Millisecond {
+		t.Errorf("expected 2 failures and a backoff of 2ms, got %+v", state)
+	}
+	rt.Exec()
+	if state.ConsecutiveFailures != 4 || state.LastBackoff != 8*time.Millisecond {
+		t.Errorf("expected 4 failures and a backoff of 8ms, got %+v", state)
+	}
+}
+
+func TestPersistentStateResetOnSuccess(t *testing.T) {
+	state := State{ConsecutiveFailures: 3, LastBackoff: time.Millisecond}
+	load, save := saveTo(&state)
+	Retry(func() error { return nil }).PersistentState(load, save).Exec()
+
+	if state != (State{}) {
+		t.Errorf("expected the zero State, got %+v", state)
+	}
+}
+
+func TestPersistentStateLastBackoffWithoutSideEffects(t *testing.T) {
+	var state State
+	load, save := saveTo(&state)
+	RetryControlled(context.Background(), func(_ context.Context, c Controller) error {
+		c.SetNextDelay(time.Hour)
+		return errors.New("failed")
+	}).SetRetries(1).SetSleep(time.Millisecond).ReplayBackoff([]time.Duration{time.Minute}).
+		PersistentState(load, save).Exec()
+
+	if state.LastBackoff != time.Millisecond {
+		t.Errorf("expected the backoff of SetSleep, got %v", state.LastBackoff)
+	}
+
+	state = State{}
+	Retry(func() error { return errors.New("failed") }).
+		SetRetries(1).FullJitter(100*time.Millisecond, time.Second).PersistentState(load, save).Exec()
+	if state.LastBackoff != 100*time.Millisecond {
+		t.Errorf("expected the largest delay of the jitter, got %v", state.LastBackoff)
+	}
+}
+
+func TestPersistentStatePanic(t *testing.T) {
+	var panics atomic.Int32
+	panics.Store(1)
+	var saved []State
+	Retry(func() error { return errors.New("failed") }).
+		SetRetries(2).SetBackoff(panicBackoff{panics: &panics}).
+		PersistentState(func() State { return State{} }, func(s State) { saved = append(saved, s) }).
+		Exec()
+
+	if len(saved) != 1 || saved[0].ConsecutiveFailures != 1 {
+		t.Errorf("expected one saved State with 1 failure, got %+v", saved)
+	}
+}
@@ -0,0 +1,110 @@
+package retryable
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is encoded in JSON as a string like "3s",
+// a number is also accepted as nanoseconds when decoding.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*d = Duration(value)
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+// The Type field of BackoffPolicy is the name of the strategy, one of
+// "constant" (sleeps Base), "exponential" and "full-jitter" (see Exponential
+// and FullJitter), and Base and Max are their parameters.
+type BackoffPolicy struct {
+	Type string   `json:"type"`
+	Base Duration `json:"base,omitempty"`
+	Max  Duration `json:"max,omitempty"`
+}
+
+// Policy is a serializable configuration of a Retrayable, to define the retry
+// policies in configuration files and tune them without recompiling:
+//
+//	{"retries": 3, "timeout": "10s", "backoff": {"type": "exponential", "base": "100ms", "max": "5s"}}
+//
+// The Backoff field takes precedence over Sleep. The zero fields are not
+// applied by ApplyPolicy, the instance keeps its setting for them.
+type Policy struct {
+	Retries    int            `json:"retries,omitempty"`
+	Sleep      Duration       `json:"sleep,omitempty"`
+	Timeout    Duration       `json:"timeout,omitempty"`
+	Backoff    *BackoffPolicy `json:"backoff,omitempty"`
+	MaxElapsed Duration       `json:"max_elapsed,omitempty"`
+}
+
+// The function ParsePolicy decodes a Policy from JSON, the durations are
+// strings like "3s". Unknown fields and backoff types are rejected with an
+// error that wraps ErrInvalidConfig.
+func ParsePolicy(data []byte) (Policy, error) {
+	var p Policy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return Policy{}, fmt.Errorf("%w: policy: %v", ErrInvalidConfig, err)
+	}
+	if p.Backoff != nil {
+		switch p.Backoff.Type {
+		case "constant", "exponential", "full-jitter":
+		default:
+			return Policy{}, fmt.Errorf("%w: policy: unknown backoff type %q", ErrInvalidConfig, p.Backoff.Type)
+		}
+	}
+	return p, nil
+}
+
+// The ApplyPolicy method applies the non zero settings of p. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) ApplyPolicy(p Policy) RetrayableI {
+	if p.Retries > 0 {
+		r.SetRetries(p.Retries)
+	}
+	if p.Sleep > 0 {
+		r.SetSleep(time.Duration(p.Sleep))
+	}
+	if p.Timeout > 0 {
+		r.SetTimeout(time.Duration(p.Timeout))
+	}
+	if p.MaxElapsed > 0 {
+		r.MaxElapsed(time.Duration(p.MaxElapsed))
+	}
+	if b := p.Backoff; b != nil {
+		switch b.Type {
+		case "constant":
+			r.SetBackoff(Constant(time.Duration(b.Base)))
+		case "exponential":
+			r.SetBackoff(Exponential(time.Duration(b.Base), time.Duration(b.Max)))
+		case "full-jitter":
+			r.FullJitter(time.Duration(b.Base), time.Duration(b.Max))
+		default:
+			r.configErr = fmt.Errorf("%w: ApplyPolicy: unknown backoff type %q", ErrInvalidConfig, b.Type)
+		}
+	}
+	return r
+}
@@ -0,0 +1,58 @@
+package retryable
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPolicyRoundTrip(t *testing.T) {
+	p := Policy{
+		Retries:    3,
+		Sleep:      Duration(time.Second),
+		Timeout:    Duration(10 * time.Second),
+		Backoff:    &BackoffPolicy{Type: "exponential", Base: Duration(100 * time.Millisecond), Max: Duration(5 * time.Second)},
+		MaxElapsed: Duration(time.Minute),
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := ParsePolicy(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, p) {
+		t.Errorf("expected %+v, got %+v from %s", p, parsed, data)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(`{"retries": 3, "timeout": "10s", "sleep": 1000000}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Retries != 3 || p.Timeout != Duration(10*time.Second) || p.Sleep != Duration(time.Millisecond) {
+		t.Errorf("unexpected policy %+v", p)
+	}
+
+	for _, data := range []string{`{"retries": 3, "unknown": 1}`, `{"backoff": {"type": "linear"}}`, `{"sleep": "forever"}`} {
+		if _, err := ParsePolicy([]byte(data)); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: expected %v, got %v", data, ErrInvalidConfig, err)
+		}
+	}
+}
+
+func TestApplyPolicy(t *testing.T) {
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return errors.New("failed")
+	}).SetRetries(10).ApplyPolicy(Policy{Retries: 2, Backoff: &BackoffPolicy{Type: "constant"}}).Exec()
+
+	if calls != 2 || stats.Attempts != 2 {
+		t.Errorf("expected the 2 attempts of the policy, got %d", calls)
+	}
+}
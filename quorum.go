@@ -0,0 +1,150 @@
+package retryable
+
+import (
+	"context"
+	"time"
+)
+
+// parallelResult is the result of an attempt of ParallelQuorum.
+type parallelResult struct {
+	attempt   int
+	err       error
+	timeout   time.Duration
+	timedOut  bool
+	cancelled bool
+}
+
+// execParallel runs the limit attempts of the quorum mode at the same time
+// and cancels the ones still running once the quorum is reached or becomes
+// impossible.
+func (r *Retrayable) execParallel(ctx context.Context, limit int, progress chan Stats) Stats {
+	attemptsCtx, cancelAttempts := context.WithCancel(ctx)
+	defer cancelAttempts()
+
+	results := make(chan parallelResult, limit)
+	var started Stats
+	for i := 0; i < limit; i++ {
+		if r.group != nil && !r.group.take() {
+			limit = i
+			break
+		}
+		inFlight := r.inFlight
+		if inFlight != nil && !r.acquireInFlight(ctx, inFlight) {
+			limit = i
+			break
+		}
+		if r.limiter != nil && r.limiter.Acquire(ctx, 1) != nil {
+			if inFlight != nil {
+				<-inFlight
+			}
+			limit = i
+			break
+		}
+		r.recordStart(&started)
+		timeout := r.attemptTimeout(i, nil)
+		go func(attempt int, inFlight chan struct{}, limiter Limiter) {
+			attemptCtx, cancel := context.WithCancel(attemptsCtx)
+			defer cancel()
+			hard, stop := r.timer(timeout)
+			defer stop()
+			ch := make(chan error, 1)
+			go func() {
+				if inFlight != nil {
+					defer func() { <-inFlight }()
+				}
+				if limiter != nil {
+					defer limiter.Release(1)
+				}
+				ch <- r.runner.Run(attemptCtx, func() error { return r.fn(attemptCtx, attempt) })
+			}()
+			select {
+			case err := <-ch:
+				results <- parallelResult{attempt: attempt, err: err}
+			case <-hard:
+				r.watchLate(ch)
+				results <- parallelResult{attempt: attempt, err: r.timeoutError(attempt), timeout: timeout, timedOut: true}
+			case <-attemptCtx.Done():
+				r.watchLate(ch)
+				results <- parallelResult{attempt: attempt, cancelled: true}
+			}
+		}(i, inFlight, r.limiter)
+	}
+
+	required := r.quorum
+	if required < 1 {
+		required = 1
+	}
+	stats := Stats{Attempts: limit, Retries: limit - 1, Fields: r.runFields(), AttemptTimes: started.AttemptTimes}
+	failures, decided := 0, false
+	// the attempt that stopped the execution before the quorum was decided
+	var stop *Stats
+	for i := 0; i < limit; i++ {
+		result := <-results
+		if result.cancelled {
+			stats.CancelledAttempts++
+			continue
+		}
+		var final Outcome
+		decision := verdictRetry
+		if result.timedOut {
+			failures++
+			stats.Err = result.err
+			stats.Timeout++
+			stats.TimeoutDuration += result.timeout
+			r.collect(&stats, ReasonTimeout)
+			if r.abortTimeout || r.nonIdempotent {
+				decision, final = verdictAbort, OutcomeTimeout
+			}
+		} else {
+			var err error
+			var reason Reason
+			err, reason, decision = r.judge(result.err)
+			switch {
+			case decision == verdictSuccess || decision == verdictStop && err == nil:
+				stats.Successes++
+				if stats.succeeded == 0 {
+					stats.succeeded = result.attempt + 1
+				}
+			default:
+				failures++
+				stats.Err = err
+				r.collect(&stats, reason)
+				final = OutcomeAborted
+			}
+		}
+		r.report(progress, stats)
+		if decided {
+			continue
+		}
+		switch {
+		case decision == verdictStop, decision == verdictAbort:
+			stopped := stats
+			stopped.Outcome = final
+			stop = &stopped
+		case stats.Successes < required && limit-failures >= required:
+			continue
+		}
+		decided = true
+		cancelAttempts()
+	}
+
+	switch {
+	case stop != nil:
+		stats.Err, stats.Outcome = stop.Err, stop.Outcome
+		if stop.Err == nil {
+			stats.Outcome = OutcomeSuccess
+		}
+	case limit == 0 && ctx.Err() == nil:
+		stats.Err = ErrGroupBudgetExhausted
+		stats.Outcome = OutcomeExhausted
+	case stats.Successes >= required:
+		stats.Err = nil
+		stats.Outcome = OutcomeSuccess
+	case ctx.Err() != nil:
+		return interrupted(ctx, stats)
+	default:
+		stats.Err = ErrQuorumImpossible
+		stats.Outcome = OutcomeQuorumImpossible
+	}
+	return stats
+}
@@ -0,0 +1,140 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelQuorumValueOfTheSuccessfulAttempt(t *testing.T) {
+	var calls atomic.Int32
+	stats := RetryValue(func() (string, error) {
+		switch calls.Add(1) {
+		case 1:
+			time.Sleep(20 * time.Millisecond)
+			return "ok", nil
+		default:
+			return "failed", errors.New("failed")
+		}
+	}).Configure(func(rt RetrayableI) { rt.SetRetries(3).ParallelQuorum(true) }).Exec()
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if stats.Value != "ok" {
+		t.Errorf("expected the value of the successful attempt, got %q", stats.Value)
+	}
+}
+
+func TestParallelQuorumAbortOn(t *testing.T) {
+	errFatal := errors.New("fatal")
+	var calls atomic.Int32
+	stats := RetryContext(context.Background(), func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			return errFatal
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}).SetRetries(3).Quorum(2).ParallelQuorum(true).AbortOn(errFatal).Exec()
+
+	if !errors.Is(stats.Err, errFatal) {
+		t.Errorf("expected %v, got %v", errFatal, stats.Err)
+	}
+	if stats.Outcome != OutcomeAborted {
+		t.Errorf("expected outcome %v, got %v", OutcomeAborted, stats.Outcome)
+	}
+}
+
+func TestParallelQuorumTreatAsSuccess(t *testing.T) {
+	errDone := errors.New("already done")
+	stats := Retry(func() error { return errDone }).
+		SetRetries(3).Quorum(2).ParallelQuorum(true).TreatAsSuccess(errDone).Exec()
+
+	if stats.Err != nil {
+		t.Errorf("unexpected error: %v", stats.Err)
+	}
+	if stats.Successes < 2 {
+		t.Errorf("expected at least 2 successes, got %d", stats.Successes)
+	}
+}
+
+func TestParallelQuorumMaxInFlight(t *testing.T) {
+	var inFlight gauge
+	stats := Retry(func() error {
+		defer inFlight.enter()()
+		time.Sleep(10 * time.Millisecond)
+		return errors.New("failed")
+	}).SetRetries(4).Quorum(1).ParallelQuorum(true).MaxInFlight(2, nil).Exec()
+
+	if stats.Err == nil {
+		t.Fatal("expected an error")
+	}
+	if inFlight.peak.Load() > 2 {
+		t.Errorf("expected at most 2 attempts in flight, got %d", inFlight.peak.Load())
+	}
+}
+
+func TestQuorumReached(t *testing.T) {
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		if calls%2 == 0 {
+			return errors.New("failed")
+		}
+		return nil
+	}).SetRetries(5).Quorum(3).Exec()
+
+	if stats.Err != nil || stats.Outcome != OutcomeSuccess {
+		t.Fatalf("unexpected error: %v (%v)", stats.Err, stats.Outcome)
+	}
+	if stats.Successes != 3 || stats.Attempts != 5 {
+		t.Errorf("expected 3 successes in 5 attempts, got %d in %d", stats.Successes, stats.Attempts)
+	}
+}
+
+func TestQuorumImpossibleExitsEarly(t *testing.T) {
+	stats := Retry(func() error { return errors.New("failed") }).SetRetries(5).Quorum(3).Exec()
+
+	if !errors.Is(stats.Err, ErrQuorumImpossible) || stats.Outcome != OutcomeQuorumImpossible {
+		t.Errorf("expected %v, got %v (%v)", ErrQuorumImpossible, stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 3 {
+		t.Errorf("expected to stop after the third failure, got %d attempts", stats.Attempts)
+	}
+}
+
+func TestParallelQuorumCancelsTheRunningAttempts(t *testing.T) {
+	var calls, cancelled atomic.Int32
+	start := time.Now()
+	stats := RetryContext(context.Background(), func(ctx context.Context) error {
+		if calls.Add(1) <= 2 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			cancelled.Add(1)
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.New("not cancelled")
+		}
+	}).SetRetries(5).Quorum(2).ParallelQuorum(true).Exec()
+
+	if stats.Err != nil || stats.Outcome != OutcomeSuccess {
+		t.Fatalf("unexpected error: %v (%v)", stats.Err, stats.Outcome)
+	}
+	if stats.CancelledAttempts != 3 {
+		t.Errorf("expected 3 cancelled attempts, got %d", stats.CancelledAttempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to return once the quorum was reached, took %v", elapsed)
+	}
+	deadline := time.Now().Add(time.Second)
+	for cancelled.Load() != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if cancelled.Load() != 3 {
+		t.Errorf("expected the context of the 3 running attempts to be cancelled, got %d", cancelled.Load())
+	}
+}
@@ -0,0 +1,52 @@
+package retryable
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateWindow keeps the start times of the recent retries of RetryRate.
+type rateWindow struct {
+	mu    sync.Mutex
+	max   int
+	per   time.Duration
+	times []time.Time
+}
+
+func newRateWindow(max int, per time.Duration) *rateWindow {
+	return &rateWindow{max: max, per: per}
+}
+
+// reserve records a retry at now if the window allows it, otherwise it
+// returns how long to wait until the oldest retry leaves the window.
+func (w *rateWindow) reserve(now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	recent := w.times[:0]
+	for _, t := range w.times {
+		if now.Sub(t) < w.per {
+			recent = append(recent, t)
+		}
+	}
+	w.times = recent
+	if len(w.times) < w.max {
+		w.times = append(w.times, now)
+		return 0
+	}
+	return w.times[0].Add(w.per).Sub(now)
+}
+
+// waitRate waits until the RetryRate allows a new retry, it returns false if
+// the context is done before.
+func (r *Retrayable) waitRate(ctx context.Context) bool {
+	for {
+		wait := r.rate.reserve(time.Now())
+		if wait <= 0 {
+			return true
+		}
+		if !r.wait(ctx, wait) {
+			return false
+		}
+	}
+}
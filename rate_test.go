@@ -0,0 +1,34 @@
+package retryable
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateWindow(t *testing.T) {
+	w := newRateWindow(2, time.Minute)
+	now := time.Now()
+	if w.reserve(now) != 0 || w.reserve(now.Add(time.Second)) != 0 {
+		t.Fatal("expected the first 2 retries to be allowed")
+	}
+	if wait := w.reserve(now.Add(10 * time.Second)); wait != 50*time.Second {
+		t.Errorf("expected to wait for the oldest retry to leave the window, got %v", wait)
+	}
+	if wait := w.reserve(now.Add(time.Minute)); wait != 0 {
+		t.Errorf("expected the retry to be allowed once the oldest left, got %v", wait)
+	}
+}
+
+func TestRetryRate(t *testing.T) {
+	start := time.Now()
+	stats := Retry(func() error { return errors.New("failed") }).
+		SetRetries(6).RetryRate(2, 50*time.Millisecond).Exec()
+
+	if stats.Retries != 5 {
+		t.Fatalf("expected 5 retries, got %d", stats.Retries)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the retries to be delayed to 2 per 50ms, took %v", elapsed)
+	}
+}
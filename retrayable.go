@@ -67,21 +67,325 @@ package retryable
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// UNCLASSIFIED_CATEGORY is the category of Stats.ErrorCounts of the errors
+// that the Classify function does not classify.
+const UNCLASSIFIED_CATEGORY = "other"
+
 // Errors String constants
 const (
-	CANCEL_ERROR  = "Function cancelled"
-	TIMEOUT_ERROR = "Function timeout"
+	CANCEL_ERROR            = "Function cancelled"
+	TIMEOUT_ERROR           = "Function timeout"
+	ALREADY_CANCELLED_ERROR = "Function already cancelled"
+	RETRY_REQUESTED_ERROR   = "Function requested a retry"
+	QUORUM_IMPOSSIBLE_ERROR = "Function quorum can not be reached"
+	DEADLINE_ERROR          = "Function deadline exceeded"
+	EXHAUSTED_ERROR         = "Function max attempts exhausted"
+	CIRCUIT_OPEN_ERROR      = "Function circuit breaker is open"
+	PANIC_ERROR             = "Function execution panicked"
+	INVALID_CONFIG_ERROR    = "Invalid configuration"
+	UNSATISFIED_ERROR       = "Function success condition not met"
+	GROUP_BUDGET_ERROR      = "Function group attempt budget exhausted"
+	NIL_FUNC_ERROR          = "Function is nil"
+	STOP_FLAG_ERROR         = "Function stop flag set"
 )
 
+// ErrCancelled is returned by Exec when the execution is cancelled, the
+// returned error also wraps the error of the context (context.Canceled).
+var ErrCancelled = errors.New(CANCEL_ERROR)
+
+// ErrTimeout is the error of an attempt that took longer than the timeout.
+var ErrTimeout = errors.New(TIMEOUT_ERROR)
+
+// ErrDeadline is returned by Exec when the deadline of the execution passed,
+// the returned error also wraps context.DeadlineExceeded.
+var ErrDeadline = errors.New(DEADLINE_ERROR)
+
+// ErrExhausted is returned by Exec when the AbsoluteMaxAttempts cap is hit or
+// a BudgetFor budget is exhausted, the returned error also wraps the error of
+// the last attempt.
+var ErrExhausted = errors.New(EXHAUSTED_ERROR)
+
+// ErrInvalidConfig is returned by Exec without executing the function when a
+// setting is invalid, the returned error also describes the setting.
+var ErrInvalidConfig = errors.New(INVALID_CONFIG_ERROR)
+
+// ErrPanic is returned by Exec when the execution panicked, the returned
+// error also describes the panic.
+var ErrPanic = errors.New(PANIC_ERROR)
+
+// ErrCircuitOpen is returned by Exec without executing the function while the
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New(CIRCUIT_OPEN_ERROR)
+
+// ErrQuorumImpossible is returned by Exec in quorum mode when the attempts
+// left can not reach the required number of successes.
+var ErrQuorumImpossible = errors.New(QUORUM_IMPOSSIBLE_ERROR)
+
+// ErrAlreadyCancelled is returned by Exec when Cancel was called before the
+// execution started, so the function has not been executed at all. It wraps
+// the error of the context, and ErrDeadline when its deadline passed.
+var ErrAlreadyCancelled = errors.New(ALREADY_CANCELLED_ERROR)
+
+// ErrRetryRequested is the error of an attempt of a RetryBool function that
+// requested a retry without returning an error.
+var ErrRetryRequested = errors.New(RETRY_REQUESTED_ERROR)
+
+// ErrUnsatisfied is the error of an attempt that returned a nil error that
+// the SuccessWhen predicate did not accept as a success.
+var ErrUnsatisfied = errors.New(UNSATISFIED_ERROR)
+
+// ErrGroupBudgetExhausted is returned by Exec when the attempt budget of its
+// Group is exhausted, see Group.SetBudget.
+var ErrGroupBudgetExhausted = errors.New(GROUP_BUDGET_ERROR)
+
+// ErrNilFunc is returned by Exec without executing anything when the
+// function given to the constructor is nil, instead of panicking inside the
+// goroutine of the first attempt.
+var ErrNilFunc = errors.New(NIL_FUNC_ERROR)
+
+// ErrStopFlag is wrapped by the error of Exec, together with ErrCancelled,
+// when the execution stops because the flag of StopWhenFlag is set.
+var ErrStopFlag = errors.New(STOP_FLAG_ERROR)
+
+// causeError is the error of an execution that finished for the reason err,
+// like a cancellation, because of cause, like the error of the context. It
+// matches both err and cause.
+type causeError struct {
+	err   error
+	cause error
+}
+
+func (e causeError) Error() string {
+	return e.err.Error() + ": " + e.cause.Error()
+}
+
+func (e causeError) Is(target error) bool {
+	return target == e.err
+}
+
+func (e causeError) Unwrap() error {
+	return e.cause
+}
+
+// stopError marks the result of an attempt that must not be retried, err is
+// the result reported in the Stats and can be nil.
+type stopError struct {
+	err error
+}
+
+func (e stopError) Error() string {
+	if e.err == nil {
+		return "<nil>"
+	}
+	return e.err.Error()
+}
+
+// retryError marks the error of an attempt that must be retried regardless
+// of the retry predicates.
+type retryError struct {
+	err error
+}
+
+func (e retryError) Error() string {
+	return e.err.Error()
+}
+
+// groupBudgetError returns ErrGroupBudgetExhausted wrapping the error of the
+// last attempt, if any.
+func groupBudgetError(last error) error {
+	if last == nil {
+		return ErrGroupBudgetExhausted
+	}
+	return causeError{err: ErrGroupBudgetExhausted, cause: last}
+}
+
+// attemptError returns the error of an attempt without its retry markers.
+func attemptError(err error) error {
+	switch marked := err.(type) {
+	case stopError:
+		return marked.err
+	case retryError:
+		return marked.err
+	}
+	return err
+}
+
 type RetrayableI interface {
 	SetTimeout(timeout time.Duration) RetrayableI
+	TimeoutFromAttempt(n int, timeout time.Duration) RetrayableI
+	TimeoutFor(matcher func(err error) bool, timeout time.Duration) RetrayableI
+	AdaptiveTimeout(factor float64) RetrayableI
+	SoftTimeout(timeout time.Duration, fn func()) RetrayableI
 	SetSleep(sleep time.Duration) RetrayableI
 	SetRetries(retries int) RetrayableI
+	RetryForever() RetrayableI
+	AbsoluteMaxAttempts(max int) RetrayableI
+	SetBackoff(backoff Backoff) RetrayableI
+	GrowthCap(attempts int) RetrayableI
+	FullJitter(base, max time.Duration) RetrayableI
+	CronSchedule(spec string) RetrayableI
+	Describe() string
+	WithFields(fields map[string]any) RetrayableI
+	ApplyPolicy(p Policy) RetrayableI
+	Schedule(n int) []DelayRange
+	SetRunner(runner Runner) RetrayableI
+	AbortOnTimeout(abort bool) RetrayableI
+	TimeoutErrorFunc(fn func(attempt int) error) RetrayableI
+	RetryIf(fn func(err error) bool) RetrayableI
+	SuccessWhen(fn func(err error) bool) RetrayableI
+	TreatAsSuccess(errs ...error) RetrayableI
+	TreatAsSuccessFunc(fn func(err error) bool) RetrayableI
+	AbortOn(errs ...error) RetrayableI
+	AbortOnFunc(fn func(err error) bool) RetrayableI
+	AbortOnRepeated(n int) RetrayableI
+	AbortOnRepeatedFunc(n int, same func(prev, err error) bool) RetrayableI
+	RetryOnMessage(pattern string) RetrayableI
+	Idempotent(idempotent bool) RetrayableI
+	SetJitter(factor float64) RetrayableI
+	JitterFunc(fn func(attempt int) float64) RetrayableI
+	InitialDelay(delay time.Duration) RetrayableI
+	Warmup(fn func() error) RetrayableI
+	WarmupMustSucceed(must bool) RetrayableI
+	InitialDelayJitter(factor float64) RetrayableI
+	ScheduleAround(t time.Time, window time.Duration) RetrayableI
+	Delays(delays ...time.Duration) RetrayableI
+	ReplayBackoff(delays []time.Duration) RetrayableI
+	WithSeed(seed int64) RetrayableI
+	WithRand(rnd *rand.Rand) RetrayableI
+	MaxInFlight(max int, onLimit func()) RetrayableI
+	WithConcurrencyLimit(limiter Limiter) RetrayableI
+	Fallback(fn func(lastErr error) error) RetrayableI
+	Escalate(fn func(lastErr error) error) RetrayableI
+	ExhaustedError(err error) RetrayableI
+	OnRetry(fn func(attempt int, err error)) RetrayableI
+	OnRetryReason(fn func(attempt int, err error, reason Reason)) RetrayableI
+	OnSuccess(fn func(stats Stats)) RetrayableI
+	OnGiveUp(fn func(stats Stats)) RetrayableI
+	Finalize(fn func(stats *Stats)) RetrayableI
+	Observe(fn func(stats Stats)) RetrayableI
+	BetweenAttempts(fn func(attempt int, err error) error) RetrayableI
+	CancelWaitsForInFlight(wait bool) RetrayableI
+	OnLateResult(fn func(err error)) RetrayableI
+	ForceFreshEachRetry(fresh bool) RetrayableI
+	Quorum(required int) RetrayableI
+	ParallelQuorum(parallel bool) RetrayableI
+	Deadline(deadline time.Time) RetrayableI
+	CancelOnSignal(signals ...os.Signal) RetrayableI
+	MaxElapsed(maxElapsed time.Duration) RetrayableI
+	ExecTimeout(timeout time.Duration) RetrayableI
+	MaxTotalSleep(maxSleep time.Duration) RetrayableI
+	RetryRate(maxRetries int, per time.Duration) RetrayableI
+	RetryWhile(fn func(attempt int, elapsed time.Duration, err error) bool) RetrayableI
+	BudgetFor(matcher func(err error) bool, max int) RetrayableI
+	StopWhen(conditions ...StopCondition) RetrayableI
+	StopWhenFlag(flag *atomic.Bool) RetrayableI
+	WithStore(store RetryStore, name string) RetrayableI
+	PersistentState(load func() State, save func(State)) RetrayableI
+	CurrentDelay() time.Duration
+	Snapshot() Stats
+	Progress() <-chan Stats
+	CollectErrors(collect bool) RetrayableI
+	RecordAttemptTimes(record bool) RetrayableI
+	Classify(fn func(err error) string) RetrayableI
+	Lifetime() LifetimeStats
+	ResetLifetime()
+	Probe(fn func() bool, interval time.Duration) RetrayableI
+	CircuitBreakerWindow(window int, failureRate float64, cooldown time.Duration) RetrayableI
+	WithSleepFunc(fn func(d time.Duration)) RetrayableI
+	WithTimeoutFunc(fn func(d time.Duration) <-chan time.Time) RetrayableI
+	WithClock(now func() time.Time) RetrayableI
+	AsFunc() func() error
 	Cancel()
 	Exec() Stats
+	ExecFuture() *Future[Stats]
+	ExecAsync() <-chan Stats
+	Done() <-chan struct{}
+}
+
+// Outcome is the reason why an execution finished.
+type Outcome int
+
+const (
+	// The function succeeded.
+	OutcomeSuccess Outcome = iota
+	// All the retries failed.
+	OutcomeExhausted
+	// The execution was cancelled.
+	OutcomeCancelled
+	// An attempt timed out and AbortOnTimeout is set.
+	OutcomeTimeout
+	// The function returned an error that must not be retried.
+	OutcomeAborted
+	// The attempts left can not reach the quorum.
+	OutcomeQuorumImpossible
+	// The deadline of the execution passed.
+	OutcomeDeadline
+	// The circuit breaker is open.
+	OutcomeCircuitOpen
+)
+
+// Reason is why an attempt failed, to tell a timeout from an error returned
+// by the function without matching the error.
+type Reason int
+
+const (
+	// The function returned an error.
+	ReasonError Reason = iota
+	// The attempt timed out, its error is ErrTimeout or the one of
+	// TimeoutErrorFunc.
+	ReasonTimeout
+	// The RetryBool function requested a retry, its error is
+	// ErrRetryRequested when it did not return one.
+	ReasonRetryRequested
+	// The function returned nil but SuccessWhen rejected it, its error is
+	// ErrUnsatisfied.
+	ReasonUnsatisfied
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonError:
+		return "Error"
+	case ReasonTimeout:
+		return "Timeout"
+	case ReasonRetryRequested:
+		return "RetryRequested"
+	case ReasonUnsatisfied:
+		return "Unsatisfied"
+	}
+	return "Unknown"
+}
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "Success"
+	case OutcomeExhausted:
+		return "Exhausted"
+	case OutcomeCancelled:
+		return "Cancelled"
+	case OutcomeTimeout:
+		return "Timeout"
+	case OutcomeAborted:
+		return "Aborted"
+	case OutcomeQuorumImpossible:
+		return "QuorumImpossible"
+	case OutcomeDeadline:
+		return "Deadline"
+	case OutcomeCircuitOpen:
+		return "CircuitOpen"
+	}
+	return "Unknown"
 }
 
 // The Err field is an error that represents the result of the function 
@@ -91,19 +395,255 @@ type RetrayableI interface {
 // function was retried before it either succeeded or failed permanently.
 // The Timeout field is an integer that represents the number of times the 
 // function was timed out before it either succeeded or failed permanently.
+// The TimeoutDuration field is the total time lost waiting for the attempts
+// that timed out, it adds the configured timeout for each of them.
+// The UsedFallback field is true when all the retries failed and the result
+// is the one of the Fallback function.
+// The Successes field is the number of attempts that succeeded, it is only
+// greater than one in quorum mode.
+// The Outcome field is the reason why the execution finished.
+// The Attempts field is the number of times the function was executed, the
+// first execution included, so when it is not zero Retries is Attempts - 1.
+// The FirstAttemptDuration field is the duration of the first attempt, the
+// cold one, whatever its outcome, and the RetriesDuration field is the total
+// duration of the other attempts. A timed out or cancelled attempt lasts
+// until Exec stops waiting for it.
+// The Skipped field is the number of attempts that were not executed at all,
+// it counts every failed check of the Probe and the execution rejected by an
+// open circuit breaker (see CircuitBreakerWindow), which skips one attempt.
+// The RemainingRetries field is the number of attempts still allowed when the
+// execution was cancelled or its deadline passed, to tell whether the
+// deadline or the number of retries is the binding constraint. It is zero in
+// any other case and when retrying forever.
+// The CancelledAttempts field is the number of attempts of ParallelQuorum
+// cancelled once the quorum was decided.
+// The Errors field has the errors of all the failed attempts when
+// CollectErrors is enabled, and the Reasons field has the Reason of each of
+// them, at the same index.
+// The Fields field has the fields set with WithFields, it is shared by all
+// the Stats of an execution and must not be modified.
+// The Elapsed field is the time the execution took, or has taken so far in a
+// Snapshot. It is not set in the snapshots sent to Progress.
+// The ErrorCounts field has the number of failed attempts of each category
+// returned by the Classify function, it is nil without one.
+// The AttemptTimes field has the time each attempt started, in the order of
+// the attempts, when RecordAttemptTimes is enabled.
 type Stats struct {
-	Err     error
-	Retries int
-	Timeout int
+	Err                  error
+	Attempts             int
+	Retries              int
+	Timeout              int
+	TimeoutDuration      time.Duration
+	UsedFallback         bool
+	Successes            int
+	Outcome              Outcome
+	FirstAttemptDuration time.Duration
+	RetriesDuration      time.Duration
+	Skipped              int
+	RemainingRetries     int
+	CancelledAttempts    int
+	Errors               []error
+	Reasons              []Reason
+	Fields               map[string]any
+	Elapsed              time.Duration
+	ErrorCounts          map[string]int
+	AttemptTimes         []time.Time
+	// succeeded is the number of the attempt that succeeded (starting at 1),
+	// 0 when no attempt did, even if a Fallback recovered the execution.
+	succeeded int
+}
+
+// The ReasonCode method returns a short reason of the Outcome from a fixed
+// set, to be used as a metric label without blowing up its cardinality:
+//   - "success": OutcomeSuccess.
+//   - "timeout": OutcomeTimeout.
+//   - "cancelled": OutcomeCancelled.
+//   - "exhausted": OutcomeExhausted and OutcomeQuorumImpossible.
+//   - "aborted": OutcomeAborted and OutcomeCircuitOpen.
+//   - "deadline": OutcomeDeadline.
+//
+// New outcomes are mapped to one of them, the set does not grow.
+func (s Stats) ReasonCode() string {
+	switch s.Outcome {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeTimeout:
+		return "timeout"
+	case OutcomeCancelled:
+		return "cancelled"
+	case OutcomeExhausted, OutcomeQuorumImpossible:
+		return "exhausted"
+	case OutcomeDeadline:
+		return "deadline"
+	}
+	return "aborted"
+}
+
+// The Retried method returns true if the execution needed any retry, that is
+// Attempts > 1.
+func (s Stats) Retried() bool {
+	return s.Attempts > 1
+}
+
+// The TimedOut method returns true if any attempt of the execution timed out,
+// that is Timeout > 0, even if a later attempt succeeded.
+func (s Stats) TimedOut() bool {
+	return s.Timeout > 0
+}
+
+// The IsSuccess method returns true if the execution succeeded, its
+// ReasonCode is "success".
+func (s Stats) IsSuccess() bool {
+	return s.ReasonCode() == "success"
+}
+
+// The IsCancelled method returns true if the execution was cancelled, its
+// ReasonCode is "cancelled".
+func (s Stats) IsCancelled() bool {
+	return s.ReasonCode() == "cancelled"
+}
+
+// The IsTimeout method returns true if the execution stopped because of a
+// timeout, like with AbortOnTimeout, its ReasonCode is "timeout". Unlike
+// TimedOut it is false when only earlier attempts timed out.
+func (s Stats) IsTimeout() bool {
+	return s.ReasonCode() == "timeout"
+}
+
+// The IsExhausted method returns true if the execution gave up because its
+// attempts failed or its quorum was impossible, its ReasonCode is "exhausted".
+func (s Stats) IsExhausted() bool {
+	return s.ReasonCode() == "exhausted"
+}
+
+// The IsAborted method returns true if the execution was aborted or skipped
+// by the circuit breaker, its ReasonCode is "aborted".
+func (s Stats) IsAborted() bool {
+	return s.ReasonCode() == "aborted"
+}
+
+// The IsDeadline method returns true if the execution stopped at its
+// deadline, its ReasonCode is "deadline". Exactly one of the Is methods is
+// true for every Outcome.
+func (s Stats) IsDeadline() bool {
+	return s.ReasonCode() == "deadline"
+}
+
+// Runner is an extension point to control how every attempt of the function
+// is executed. Exec calls Run from a new goroutine for each attempt and waits
+// for its result, the timeout or the cancellation, whichever comes first. Run
+// receives a context that is done when the attempt finishes or times out and
+// when the execution is cancelled or its deadline passes, and the attempt to
+// execute, it must return the error of the attempt. If Run does not return
+// before the timeout or the cancellation, its result is discarded.
+//
+// The default runner just calls fn in the attempt goroutine.
+type Runner interface {
+	Run(ctx context.Context, fn func() error) error
+}
+
+type defaultRunner struct{}
+
+func (defaultRunner) Run(_ context.Context, fn func() error) error {
+	return fn()
 }
 
 type Retrayable struct {
-	fn            func() error
-	retries       int
-	sleep         time.Duration
-	timeout       time.Duration
-	cancelContext context.Context
-	cancelFn      context.CancelFunc
+	fn              func(ctx context.Context, attempt int) error
+	settle          func(attempt int, timedOut bool)
+	runner          Runner
+	retries         int
+	forever         bool
+	maxAttempts     int
+	backoff         Backoff
+	replay          []time.Duration
+	growthCap       int
+	jitter          float64
+	jitterFn        func(attempt int) float64
+	initialDelay    time.Duration
+	initialJitter   float64
+	warmup          func() error
+	warmupMust      bool
+	scheduleAt      time.Time
+	scheduleWindow  time.Duration
+	rnd             *rand.Rand
+	rndMu           sync.Mutex
+	timeout         time.Duration
+	timeoutFrom     int
+	timeoutsFor     []errorTimeout
+	softTimeout     time.Duration
+	onSoftTimeout   func()
+	abortTimeout    bool
+	timeoutErrFn    func(attempt int) error
+	retryIf         func(err error) bool
+	successWhen     func(err error) bool
+	successErrs     []error
+	successFunc     func(err error) bool
+	abortErrs       []error
+	abortFunc       func(err error) bool
+	repeatMax       int
+	repeatSame      func(prev, err error) bool
+	configErr       error
+	patternErr      error
+	nonIdempotent   bool
+	inFlight        chan struct{}
+	onInFlight      func()
+	limiter         Limiter
+	fallback        func(lastErr error) error
+	escalate        func(lastErr error) error
+	exhaustedErr    error
+	onRetry         func(attempt int, err error)
+	onRetryReason   func(attempt int, err error, reason Reason)
+	onSuccess       func(stats Stats)
+	onGiveUp        func(stats Stats)
+	finalize        func(stats *Stats)
+	observers       []func(stats Stats)
+	betweenAttempts func(attempt int, err error) error
+	cancelWaits     bool
+	onLate          func(err error)
+	forceFresh      bool
+	quorum          int
+	parallelQuorum  bool
+	collectErrors   bool
+	recordTimes     bool
+	classify        func(err error) string
+	fields          map[string]any
+	snapshotMu      sync.Mutex
+	doneMu          sync.Mutex
+	done            chan struct{}
+	doneClosed      bool
+	snapshot        Stats
+	started         time.Time
+	running         bool
+	deadline        time.Time
+	maxElapsed      time.Duration
+	execTimeout     time.Duration
+	signals         []os.Signal
+	maxTotalSleep   time.Duration
+	rate            *rateWindow
+	retryWhile      func(attempt int, elapsed time.Duration, err error) bool
+	budgets         []budget
+	stopWhen        []StopCondition
+	stopFlag        *atomic.Bool
+	store           RetryStore
+	storeName       string
+	loadState       func() State
+	saveState       func(State)
+	group           *Group
+	currentDelay    atomic.Int64
+	probe           func() bool
+	probeInterval   time.Duration
+	sleepFn         func(d time.Duration)
+	timeoutFn       func(d time.Duration) <-chan time.Time
+	now             func() time.Time
+	adaptive        *latencyWindow
+	control         controller
+	breaker         *breaker
+	lifetime        lifetime
+	progress        chan Stats
+	progressMu      sync.Mutex
+	cancelContext   context.Context
+	cancelFn        context.CancelFunc
 }
 
 // The SetTimeout method sets a time duration for the maximum amount of 
@@ -111,71 +651,2015 @@ type Retrayable struct {
 // It returns a RetrayableI instance, allowing method chaining.
 func (r *Retrayable) SetTimeout(timeout time.Duration) RetrayableI {
 	r.timeout = timeout
+	r.timeoutFrom = 0
+	return r
+}
+
+// The AdaptiveTimeout method computes the timeout of every attempt from the
+// latencies of the last ADAPTIVE_WINDOW successful attempts of the instance,
+// across its executions: the timeout is their 95th percentile times factor.
+// While there are less than ADAPTIVE_MIN_SAMPLES latencies, during the warm
+// up, the timeout of SetTimeout applies. TimeoutFromAttempt and the timeouts
+// set through a Controller still apply. A factor of zero disables it. It
+// returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) AdaptiveTimeout(factor float64) RetrayableI {
+	r.adaptive = nil
+	if factor > 0 {
+		r.adaptive = newLatencyWindow(factor)
+	}
+	return r
+}
+
+// The TimeoutFromAttempt method sets the timeout like SetTimeout, but it only
+// applies from the attempt number n (starting at 1), the previous attempts
+// can run for as long as they need. By default the timeout applies to all
+// the attempts. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) TimeoutFromAttempt(n int, timeout time.Duration) RetrayableI {
+	r.timeout = timeout
+	r.timeoutFrom = n
+	return r
+}
+
+// errorTimeout is the timeout of the attempts that follow an error that
+// matches it.
+type errorTimeout struct {
+	matcher func(err error) bool
+	timeout time.Duration
+}
+
+// The TimeoutFor method adds a timeout for the attempts that follow a failed
+// attempt whose error matches matcher, for example a longer one after a
+// timeout and a short one after a connection refused. The matchers are
+// evaluated with the error of the previous attempt in the order they were
+// added and the first one that matches sets the timeout, a timed out attempt
+// is matched as ErrTimeout, or the error of TimeoutErrorFunc. When none
+// matches, and for the first attempt, the timeout of SetTimeout,
+// TimeoutFromAttempt or AdaptiveTimeout applies. The timeouts set through a
+// Controller take precedence over it. It returns a RetrayableI instance,
+// allowing method chaining.
+func (r *Retrayable) TimeoutFor(matcher func(err error) bool, timeout time.Duration) RetrayableI {
+	r.timeoutsFor = append(r.timeoutsFor, errorTimeout{matcher: matcher, timeout: timeout})
+	return r
+}
+
+// The SoftTimeout method sets a soft timeout for every attempt, when an
+// attempt takes longer than it fn is executed but, unlike the timeout of
+// SetTimeout, Exec keeps waiting for the attempt. It is useful to log slow
+// attempts that eventually succeed. A soft timeout that is not shorter than
+// the timeout is invalid and makes Exec return ErrInvalidConfig. It returns
+// a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) SoftTimeout(timeout time.Duration, fn func()) RetrayableI {
+	r.softTimeout = timeout
+	r.onSoftTimeout = fn
 	return r
 }
 
+// validate returns the error of an invalid configuration, if any.
+func (r *Retrayable) validate() error {
+	if r.configErr != nil {
+		return r.configErr
+	}
+	if r.patternErr != nil {
+		return r.patternErr
+	}
+	if invalid, ok := r.backoff.(interface{ validate() error }); ok {
+		return invalid.validate()
+	}
+	if limit, _ := r.attempts(); r.parallelQuorum && limit < 0 {
+		return fmt.Errorf("%w: ParallelQuorum needs a finite number of attempts", ErrInvalidConfig)
+	}
+	if r.softTimeout > 0 && r.timeout > 0 && r.softTimeout >= r.timeout {
+		return fmt.Errorf("%w: SoftTimeout %s is not shorter than the timeout %s", ErrInvalidConfig, r.softTimeout, r.timeout)
+	}
+	return nil
+}
+
 // The SetRetries method sets the maximum number of times the function can 
 // be retried if it fails. It returns a RetrayableI instance, allowing method 
 // chaining.
 func (r *Retrayable) SetRetries(retries int) RetrayableI {
 	r.retries = retries
+	r.forever = false
+	return r
+}
+
+// The RetryForever method retries the function until it succeeds, it
+// replaces the value of SetRetries. It returns a RetrayableI instance,
+// allowing method chaining.
+func (r *Retrayable) RetryForever() RetrayableI {
+	r.forever = true
 	return r
 }
 
+// The AbsoluteMaxAttempts method caps the total number of attempts regardless
+// of any other setting, as a safety net for RetryForever. The smaller of
+// SetRetries (when not retrying forever) and max applies, when the cap is
+// hit Exec returns ErrExhausted. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) AbsoluteMaxAttempts(max int) RetrayableI {
+	r.maxAttempts = max
+	return r
+}
+
+// attempts returns the max number of attempts, or -1 when there is no limit,
+// and if the limit is the AbsoluteMaxAttempts cap.
+func (r *Retrayable) attempts() (int, bool) {
+	limit := r.retries
+	if r.forever {
+		limit = -1
+	}
+	if r.maxAttempts > 0 && (limit < 0 || r.maxAttempts < limit) {
+		return r.maxAttempts, true
+	}
+	return limit, false
+}
+
 // The SetSleep method sets a time duration for the delay between retries. 
 // It returns a RetrayableI instance, allowing method chaining.
 func (r *Retrayable) SetSleep(sleep time.Duration) RetrayableI {
-	r.sleep = sleep
+	r.backoff = Constant(sleep)
 	return r
 }
 
-// The Cancel method cancels the execution of the function. It does not return anything.
-func (r *Retrayable) Cancel() {
-	r.cancelFn()
+// The TimeoutErrorFunc method sets a function that builds the error of every
+// timed out attempt from its zero based number, to fit the error taxonomy of
+// the application, instead of ErrTimeout. The timeouts are still counted as
+// such in the Stats whatever the error, but errors.Is(err, ErrTimeout) only
+// holds if the returned error wraps it, like
+// fmt.Errorf("attempt %d: %w", attempt, ErrTimeout). It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) TimeoutErrorFunc(fn func(attempt int) error) RetrayableI {
+	r.timeoutErrFn = fn
+	return r
 }
 
-func (r *Retrayable) GetTimeout() <-chan time.Time {
-	if r.timeout == 0 {
-		return make(<-chan time.Time)
+// timeoutError returns the error of the given timed out attempt.
+func (r *Retrayable) timeoutError(attempt int) error {
+	if r.timeoutErrFn != nil {
+		return r.timeoutErrFn(attempt)
 	}
+	return ErrTimeout
+}
 
-	return time.After(r.timeout)
+// The AbortOnTimeout method sets if the first timeout ends the execution with
+// ErrTimeout instead of retrying the function. By default a timeout is
+// retried like any other error. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) AbortOnTimeout(abort bool) RetrayableI {
+	r.abortTimeout = abort
+	return r
 }
 
-// he Exec method executes the function with the specified settings and returns a 
-// Stats struct that contains the error result of the function (if any), the number 
-// of retries attempted, and the number of timeouts that occurred.
-func (r *Retrayable) Exec() Stats {
-	var err error
-	stats := Stats{Retries: -1}
-	for i := 0; i < r.retries; i++ {
-		ch := make(chan error, 1)
-		stats.Retries += 1
-		go func() {
-			ch <- r.fn()
-		}()
-		select {
-		case err = <-ch:
-			stats.Err = err
-			if err == nil {
-				return stats
-			}
-			time.Sleep(r.sleep)
-		case <-r.GetTimeout():
-			stats.Err = errors.New(TIMEOUT_ERROR)
-			stats.Timeout++
-		case <-r.cancelContext.Done():
-			close(ch)
-			stats.Err = errors.New(CANCEL_ERROR)
-			return stats
+// The Delays method sets an explicit schedule of delays, one per attempt,
+// every delay is the sleep before the corresponding attempt, so
+// Delays(0, time.Second, 5*time.Second) executes the function a max of 3
+// times, the first one right away. It replaces the values of SetRetries,
+// RetryForever, SetSleep and InitialDelay, without delays the function is
+// executed once. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) Delays(delays ...time.Duration) RetrayableI {
+	r.backoff, r.initialDelay = Constant(0), 0
+	if len(delays) > 0 {
+		r.initialDelay = delays[0]
+	}
+	if len(delays) > 1 {
+		r.backoff = delaysBackoff(delays[1:])
+	}
+	r.retries = len(delays)
+	if r.retries < 1 {
+		r.retries = 1
+	}
+	r.forever = false
+	return r
+}
+
+// The ReplayBackoff method forces the given sequence of delays between
+// retries, for example the delays recorded in the logs of a production
+// incident, to reproduce it. The delays are used in order, the last one is
+// reused if there are more retries, and they take precedence over any other
+// delay setting, jitter included. It does not change the number of retries.
+// It is meant for debugging and testing, not for production. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) ReplayBackoff(delays []time.Duration) RetrayableI {
+	r.replay = delays
+	return r
+}
+
+// The SetBackoff method sets the strategy that computes the delay between
+// retries, it replaces the value of SetSleep. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) SetBackoff(backoff Backoff) RetrayableI {
+	r.backoff = backoff
+	return r
+}
+
+// The GrowthCap method freezes the backoff after the given number of failed
+// attempts: the later retries keep sleeping the delay of the last attempt
+// that grew it. Unlike the max of Exponential, which caps the delay at a
+// fixed duration, it caps the number of times the delay grows, so with a base
+// of 1s and a growth cap of 3 the delays are 1s, 2s, 4s, 4s, ... whatever the
+// max. It applies to any Backoff, zero means no cap. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) GrowthCap(attempts int) RetrayableI {
+	r.growthCap = attempts
+	return r
+}
+
+// growthAttempt returns the attempt to compute the delay of the backoff
+// after the given failed attempt with, frozen at the GrowthCap.
+func (r *Retrayable) growthAttempt(attempt int) int {
+	if r.growthCap > 0 && attempt >= r.growthCap {
+		return r.growthCap - 1
+	}
+	return attempt
+}
+
+// The FullJitter method sets an exponential backoff with full jitter, every
+// delay is a random duration between 0 and min(max, base*2^attempt) taken
+// from the random source of the instance (see WithSeed). It replaces the
+// value of SetSleep. It returns a RetrayableI instance, allowing method
+// chaining.
+func (r *Retrayable) FullJitter(base, max time.Duration) RetrayableI {
+	r.backoff = fullJitterBackoff{exponentialBackoff{base: base, max: max}, r.random}
+	return r
+}
+
+// The WithFields method sets contextual fields, like a correlation ID, that
+// every Stats of the execution carries in Stats.Fields, so they reach the
+// hooks that receive a Stats, OnSuccess, OnGiveUp, Finalize and Observe, the
+// snapshots of Progress and Snapshot, and any wrapper, without being repeated
+// in each callback. OnRetry and OnRetryReason do not receive a Stats, read
+// them with Snapshot().Fields, and the retryableotel package does not add
+// them to the metrics, to keep their cardinality bounded. Exec copies them
+// when it starts, so the fields of a run are immutable, changing them later
+// only affects the next runs. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) WithFields(fields map[string]any) RetrayableI {
+	r.fields = fields
+	return r
+}
+
+// runFields returns the copy of the fields for a new execution.
+func (r *Retrayable) runFields() map[string]any {
+	if r.fields == nil {
+		return nil
+	}
+	fields := make(map[string]any, len(r.fields))
+	for key, value := range r.fields {
+		fields[key] = value
+	}
+	return fields
+}
+
+// The Describe method returns a short description of the settings of the
+// instance, useful to log the configuration actually applied. It includes the
+// range of the first delay, as returned by Schedule.
+func (r *Retrayable) Describe() string {
+	limit, _ := r.attempts()
+	first := r.bounds(0)
+	return fmt.Sprintf("retries=%d backoff=%s timeout=%s jitter=%g delay=%s-%s",
+		limit, r.backoff.Name(), r.timeout, r.jitter, first.Min, first.Max)
+}
+
+// The Schedule method returns the range of the first n delays between
+// retries without running anything, so tests can assert the delays of a
+// randomized configuration. The range of every strategy is:
+//   - Constant, Exponential, Delays and SetSleep: the delay itself.
+//   - FullJitter: from 0 to min(max, base*2^attempt).
+//   - SetJitter and JitterFunc: the range of the backoff widened by the
+//     factor, from Min*(1-factor) to Max*(1+factor), never below 0.
+//   - ReplayBackoff: the replayed delay, jitter is not applied.
+//
+// Custom Backoff implementations are treated as deterministic. The delays
+// set through a Controller while running are not included.
+func (r *Retrayable) Schedule(n int) []DelayRange {
+	schedule := make([]DelayRange, n)
+	for i := range schedule {
+		schedule[i] = r.bounds(i)
+	}
+	return schedule
+}
+
+// bounds returns the range of the delay after the given failed attempt, the
+// same steps of delay without the controller.
+func (r *Retrayable) bounds(attempt int) DelayRange {
+	if len(r.replay) > 0 {
+		return bounds(delaysBackoff(r.replay), attempt)
+	}
+	delay := bounds(r.backoff, r.growthAttempt(attempt))
+	if jitter := r.jitterFactor(attempt); jitter > 0 {
+		delay.Min -= time.Duration(jitter * float64(delay.Min))
+		delay.Max += time.Duration(jitter * float64(delay.Max))
+	}
+	if delay.Min < 0 {
+		delay.Min = 0
+	}
+	return delay
+}
+
+// The RetryIf method sets a predicate that decides if the error returned by
+// an attempt must be retried, when it returns false Exec stops with that
+// error. Timeouts are not checked by the predicate. It replaces the value of
+// RetryOnMessage. It returns a RetrayableI instance, allowing method
+// chaining.
+func (r *Retrayable) RetryIf(fn func(err error) bool) RetrayableI {
+	r.retryIf = fn
+	r.patternErr = nil
+	return r
+}
+
+// The SuccessWhen method sets a predicate that decides if an attempt is a
+// success from its error, so a nil error can also be retried, for example to
+// keep polling an eventually consistent read until the value is present, for
+// up to the number of retries. An accepted error is discarded. A nil error
+// that is not accepted becomes ErrUnsatisfied and is always retried, RetryIf
+// is not checked for it. A non nil error that is not accepted is checked by
+// RetryIf as usual. The retries requested by RetryBool are not checked. It
+// returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) SuccessWhen(fn func(err error) bool) RetrayableI {
+	r.successWhen = fn
+	return r
+}
+
+// The TreatAsSuccess method sets errors that mean the attempt succeeded, like
+// a not found error when deleting, they are matched with errors.Is and Exec
+// discards them. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) TreatAsSuccess(errs ...error) RetrayableI {
+	r.successErrs = errs
+	return r
+}
+
+// The TreatAsSuccessFunc method works like TreatAsSuccess with a matcher for
+// the errors that can not be matched with errors.Is, like errors created on
+// the fly with the same code. An error is a success if it matches any of
+// both. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) TreatAsSuccessFunc(fn func(err error) bool) RetrayableI {
+	r.successFunc = fn
+	return r
+}
+
+// The AbortOn method sets errors that stop the execution without retrying,
+// they are matched with errors.Is and Exec returns them with the Aborted
+// outcome. It returns a RetrayableI instance, allowing method chaining.
+//
+// The matchers of an error are checked in this order, the first that decides
+// wins: the decision of a RetryBool function, SuccessWhen, TreatAsSuccess and
+// TreatAsSuccessFunc, AbortOn and AbortOnFunc, and finally RetryIf.
+func (r *Retrayable) AbortOn(errs ...error) RetrayableI {
+	r.abortErrs = errs
+	return r
+}
+
+// The AbortOnFunc method works like AbortOn with a matcher for the errors
+// that can not be matched with errors.Is. An error aborts if it matches any
+// of both. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) AbortOnFunc(fn func(err error) bool) RetrayableI {
+	r.abortFunc = fn
+	return r
+}
+
+// The AbortOnRepeated method stops the execution without retrying once the
+// same error is returned by n consecutive attempts, since it will likely not
+// resolve. Two errors are the same when their Error() strings are equal, the
+// timeouts included. Exec returns the last error with the Aborted outcome.
+// By default the repeated errors are retried. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) AbortOnRepeated(n int) RetrayableI {
+	return r.AbortOnRepeatedFunc(n, nil)
+}
+
+// The AbortOnRepeatedFunc method works like AbortOnRepeated, but two errors
+// are the same when same returns true for the error of the previous attempt
+// and the new one, a nil same compares the Error() strings. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) AbortOnRepeatedFunc(n int, same func(prev, err error) bool) RetrayableI {
+	r.repeatMax = n
+	r.repeatSame = same
+	return r
+}
+
+// repeats counts the consecutive attempts of AbortOnRepeated that failed
+// with the same error.
+type repeats struct {
+	err     error
+	attempt int
+	count   int
+}
+
+// repeated counts the error of the failed attempt, it returns true if it is
+// the AbortOnRepeated one.
+func (r *Retrayable) repeated(rep *repeats, attempt int, err error) bool {
+	if r.repeatMax <= 0 {
+		return false
+	}
+	same := rep.count > 0 && rep.attempt == attempt-1
+	if same && r.repeatSame != nil {
+		same = r.repeatSame(rep.err, err)
+	} else if same {
+		same = rep.err.Error() == err.Error()
+	}
+	if !same {
+		rep.count = 0
+	}
+	rep.err, rep.attempt = err, attempt
+	rep.count++
+	return rep.count >= r.repeatMax
+}
+
+// verdict is the decision taken on the result of an attempt.
+type verdict int
+
+const (
+	// The attempt failed and can be retried.
+	verdictRetry verdict = iota
+	// The attempt succeeded.
+	verdictSuccess
+	// The RetryBool function asked not to retry, it succeeded if its error
+	// is nil.
+	verdictStop
+	// The error of the attempt must not be retried.
+	verdictAbort
+)
+
+// judge decides on the result err of an attempt that did not time out with
+// the matchers of the instance, in the order documented in AbortOn. It
+// returns the error of the attempt, nil when it succeeded, and the Reason of
+// the failure.
+func (r *Retrayable) judge(err error) (error, Reason, verdict) {
+	if stop, ok := err.(stopError); ok {
+		return stop.err, ReasonError, verdictStop
+	}
+	reason, decidable := ReasonError, true
+	if force, ok := err.(retryError); ok {
+		err, reason, decidable = force.err, ReasonRetryRequested, false
+	}
+	if decidable && r.successWhen != nil {
+		if r.successWhen(err) {
+			err = nil
+		} else if err == nil {
+			err, reason, decidable = ErrUnsatisfied, ReasonUnsatisfied, false
 		}
 	}
-	return stats
+	switch {
+	case err == nil:
+		return nil, reason, verdictSuccess
+	case !decidable:
+		return err, reason, verdictRetry
+	case matches(err, r.successErrs, r.successFunc):
+		return nil, reason, verdictSuccess
+	case matches(err, r.abortErrs, r.abortFunc):
+		return err, reason, verdictAbort
+	case r.retryIf != nil && !r.retryIf(err):
+		return err, reason, verdictAbort
+	}
+	return err, reason, verdictRetry
 }
 
-// The function Retry is creating and returning an instance of the type RetrayableI.
-// The function takes an argument fn, which is a function that returns an error. 
-func Retry(fn func() error) RetrayableI {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Retrayable{fn: fn, retries: 1, cancelContext: ctx, cancelFn: cancel}
+// matches returns true if err is any of errs or fn returns true for it.
+func matches(err error, errs []error, fn func(err error) bool) bool {
+	for _, target := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return fn != nil && fn(err)
+}
+
+// The RetryOnMessage method retries only the errors whose message matches the
+// regular expression pattern, a plain substring is also a valid pattern. It
+// is a last resort for errors that can not be matched with errors.Is in
+// RetryIf. An invalid pattern makes Exec return ErrInvalidConfig, until a
+// later RetryOnMessage or RetryIf replaces it. It replaces the value of
+// RetryIf. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) RetryOnMessage(pattern string) RetrayableI {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		r.patternErr = fmt.Errorf("%w: RetryOnMessage: %v", ErrInvalidConfig, err)
+		return r
+	}
+	r.retryIf = func(err error) bool { return re.MatchString(err.Error()) }
+	r.patternErr = nil
+	return r
+}
+
+// The Idempotent method sets if the function can be safely executed more
+// than once. A timed out attempt may still succeed in the background, so when
+// the function is not idempotent a timeout is never retried and Exec returns
+// ErrTimeout immediately, like with AbortOnTimeout. Set it to false for
+// operations like payments that must not be duplicated. By default the
+// function is idempotent. It returns a RetrayableI instance, allowing method
+// chaining.
+func (r *Retrayable) Idempotent(idempotent bool) RetrayableI {
+	r.nonIdempotent = !idempotent
+	return r
+}
+
+// The SetJitter method randomizes every delay between retries by up to the
+// given factor of it, so with a factor of 0.5 a sleep of 2 seconds becomes a
+// random delay between 1 and 3 seconds. It returns a RetrayableI instance,
+// allowing method chaining.
+func (r *Retrayable) SetJitter(factor float64) RetrayableI {
+	r.jitter = factor
+	return r
+}
+
+// The JitterFunc method sets the jitter factor of every delay from the zero
+// based number of the failed attempt, for example a decreasing factor to
+// spread the first retries and converge on the later ones. The factor is
+// applied to the delay of the backoff like SetJitter, and it replaces its
+// value. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) JitterFunc(fn func(attempt int) float64) RetrayableI {
+	r.jitterFn = fn
+	return r
+}
+
+// jitterFactor returns the jitter factor of the delay after the given failed
+// attempt.
+func (r *Retrayable) jitterFactor(attempt int) float64 {
+	if r.jitterFn != nil {
+		return r.jitterFn(attempt)
+	}
+	return r.jitter
+}
+
+// The InitialDelay method sets a delay before the first attempt, for example
+// to not hit a service that has just been deployed. It counts towards the
+// Deadline and the execution is cancelled if it is cancelled while waiting.
+// It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) InitialDelay(delay time.Duration) RetrayableI {
+	r.initialDelay = delay
+	return r
+}
+
+// The InitialDelayJitter method randomizes the InitialDelay by up to the
+// given factor, like SetJitter does for the delays between retries, to spread
+// the first attempts of many clients that start at the same time. SetJitter
+// does not apply to the initial delay, by default it is not randomized. It
+// returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) InitialDelayJitter(factor float64) RetrayableI {
+	r.initialJitter = factor
+	return r
+}
+
+// The Warmup method sets a throwaway call executed once before the first
+// attempt of every execution, after InitialDelay, to establish a connection
+// apart from the measured operation. It has no timeout of its own, but when
+// the execution is cancelled or its deadline passes, like with ExecTimeout,
+// Exec stops waiting for it and returns right away, it keeps running in the
+// background. It is not an attempt, the Stats do not count it, only their
+// Elapsed time includes it. By default its error is ignored and the attempts
+// run anyway (see WarmupMustSucceed). It returns a RetrayableI instance,
+// allowing method chaining.
+func (r *Retrayable) Warmup(fn func() error) RetrayableI {
+	r.warmup = fn
+	return r
+}
+
+// runWarmup runs the Warmup in a new goroutine and returns its error, or false
+// if ctx is done before it returns.
+func (r *Retrayable) runWarmup(ctx context.Context) (error, bool) {
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	ch := make(chan error, 1)
+	go func() {
+		ch <- r.warmup()
+	}()
+	select {
+	case err := <-ch:
+		return err, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// The WarmupMustSucceed method sets if a failed Warmup aborts the execution,
+// Exec then returns its error with the Aborted outcome without executing the
+// function. By default the error of the warmup is ignored. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) WarmupMustSucceed(must bool) RetrayableI {
+	r.warmupMust = must
+	return r
+}
+
+// The ScheduleAround method delays the first attempt to a random point of the
+// window before t, from t-window to t, so the jobs of a fleet scheduled for
+// the same time do not start at once. If the point already passed the first
+// attempt starts immediately. It replaces InitialDelay and its jitter, which
+// are ignored while it is set. The random point is taken from the random
+// source of the instance (see WithSeed). It returns a RetrayableI instance,
+// allowing method chaining.
+func (r *Retrayable) ScheduleAround(t time.Time, window time.Duration) RetrayableI {
+	r.scheduleAt = t
+	r.scheduleWindow = window
+	return r
+}
+
+// firstDelay returns the time to wait before the first attempt, set by
+// ScheduleAround or InitialDelay.
+func (r *Retrayable) firstDelay() time.Duration {
+	if !r.scheduleAt.IsZero() {
+		at := r.scheduleAt.Add(-time.Duration(r.random() * float64(r.scheduleWindow)))
+		return time.Until(at)
+	}
+	delay := r.initialDelay
+	if delay > 0 && r.initialJitter > 0 {
+		delay += time.Duration((r.random()*2 - 1) * r.initialJitter * float64(delay))
+	}
+	return delay
+}
+
+// The WithSeed method seeds the random source of the instance, so the jitter
+// and every other randomized delay produces the same sequence on each run.
+// By default the source is seeded from the clock. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) WithSeed(seed int64) RetrayableI {
+	return r.WithRand(rand.New(rand.NewSource(seed)))
+}
+
+// The WithRand method sets the random source of the instance used by the
+// jitter and every other randomized delay. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) WithRand(rnd *rand.Rand) RetrayableI {
+	r.rndMu.Lock()
+	r.rnd = rnd
+	r.rndMu.Unlock()
+	return r
+}
+
+// random returns a pseudo random number in [0.0,1.0) from the source of the
+// instance.
+func (r *Retrayable) random() float64 {
+	r.rndMu.Lock()
+	defer r.rndMu.Unlock()
+	return r.rnd.Float64()
+}
+
+// delay returns the time to sleep after the given failed attempt.
+func (r *Retrayable) delay(attempt int) time.Duration {
+	if len(r.replay) > 0 {
+		return delaysBackoff(r.replay).Delay(attempt)
+	}
+	if delay, ok := r.control.takeDelay(); ok {
+		return delay
+	}
+	delay := r.backoff.Delay(r.growthAttempt(attempt))
+	if jitter := r.jitterFactor(attempt); jitter > 0 {
+		delay += time.Duration((r.random()*2 - 1) * jitter * float64(delay))
+	}
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// The MaxInFlight method caps the number of attempt goroutines of the
+// instance that can run at the same time. Attempts that timed out keep
+// running in the background, when max of them are still running Exec calls
+// onLimit (if it is not nil) and waits for one of them to finish before
+// starting a new attempt. By default there is no cap. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) MaxInFlight(max int, onLimit func()) RetrayableI {
+	r.inFlight = nil
+	if max > 0 {
+		r.inFlight = make(chan struct{}, max)
+	}
+	r.onInFlight = onLimit
+	return r
+}
+
+// acquireInFlight reserves a slot for a new attempt goroutine, it returns
+// false if the execution was cancelled while waiting for it.
+func (r *Retrayable) acquireInFlight(ctx context.Context, inFlight chan struct{}) bool {
+	select {
+	case inFlight <- struct{}{}:
+		return true
+	default:
+	}
+
+	if r.onInFlight != nil {
+		r.onInFlight()
+	}
+	select {
+	case inFlight <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// The WithConcurrencyLimit method gates the start of every attempt with
+// limiter, usually shared by many instances, or a package level one, to cap
+// the attempts running at the same time across the whole process. A
+// *semaphore.Weighted of golang.org/x/sync/semaphore is a Limiter. Every
+// attempt holds one unit of limiter until its goroutine returns, so the
+// timed out attempts that keep running in the background still count. The
+// acquisition blocks until a unit is free or the execution is cancelled, it
+// is not part of the timeout of the attempt but it counts towards the
+// Elapsed time, the Deadline and MaxElapsed of the execution. It replaces
+// the limiter of RetryBatch.LimitInFlight. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) WithConcurrencyLimit(limiter Limiter) RetrayableI {
+	r.limiter = limiter
+	return r
+}
+
+// The Fallback method sets a function that is executed once all the retries
+// failed, it receives the last error. If the fallback returns nil Exec
+// reports a success with UsedFallback set, otherwise the error of the
+// fallback is reported. It is not executed when the execution is cancelled
+// or aborted. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) Fallback(fn func(lastErr error) error) RetrayableI {
+	r.fallback = fn
+	return r
+}
+
+// The Escalate method sets a more expensive recovery path that is executed
+// once all the retries failed, it receives the last error. Unlike Fallback,
+// its success is a true success of the execution and its error becomes the
+// final error. It is executed before the Fallback, which only runs if the
+// escalation fails, and it is not executed when the execution is cancelled
+// or aborted. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) Escalate(fn func(lastErr error) error) RetrayableI {
+	r.escalate = fn
+	return r
+}
+
+// The ExhaustedError method sets the error Exec returns when the retries are
+// exhausted, wrapping the error of the last attempt, so errors.Is matches
+// both of them. It also replaces ErrExhausted. By default Exec returns the
+// error of the last attempt. It is not used when an Escalate or Fallback
+// function replaced the error. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) ExhaustedError(err error) RetrayableI {
+	r.exhaustedErr = err
+	return r
+}
+
+// The OnRetry method sets a callback executed after every failed attempt that
+// is going to be retried, it receives the zero based number of the failed
+// attempt and its error. It is executed before BetweenAttempts and the sleep.
+// It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) OnRetry(fn func(attempt int, err error)) RetrayableI {
+	r.onRetry = fn
+	return r
+}
+
+// The OnRetryReason method works like OnRetry, but the callback also receives
+// the Reason of the failure, to branch on a timeout or an error returned by
+// the function without matching the error. It is executed after the callback
+// of OnRetry. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) OnRetryReason(fn func(attempt int, err error, reason Reason)) RetrayableI {
+	r.onRetryReason = fn
+	return r
+}
+
+// The OnSuccess method sets a callback executed when Exec succeeds, with its
+// final Stats. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) OnSuccess(fn func(stats Stats)) RetrayableI {
+	r.onSuccess = fn
+	return r
+}
+
+// The OnGiveUp method sets a callback executed when Exec fails for any
+// reason, with its final Stats. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) OnGiveUp(fn func(stats Stats)) RetrayableI {
+	r.onGiveUp = fn
+	return r
+}
+
+// The Finalize method sets a hook that can enrich or adjust the Stats before
+// Exec returns them, for example to redact the error. It runs on every exit
+// path as the last step, after OnSuccess and OnGiveUp, so they and the
+// Lifetime stats see the Stats before the changes. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) Finalize(fn func(stats *Stats)) RetrayableI {
+	r.finalize = fn
+	return r
+}
+
+// The Observe method adds a function that receives the final Stats of every
+// execution, after Finalize, however it was started: Exec, ExecAsync,
+// ExecFuture or the function of AsFunc. Unlike the other hooks it does not
+// replace the previous one, every observer added is called in order, so
+// instrumentation like the retryableotel package can watch an instance
+// without taking the hooks of its owner. It returns a RetrayableI instance,
+// allowing method chaining.
+func (r *Retrayable) Observe(fn func(stats Stats)) RetrayableI {
+	if fn != nil {
+		r.observers = append(r.observers, fn)
+	}
+	return r
+}
+
+// The BetweenAttempts method sets a function executed after every failed
+// attempt that is going to be retried, to reset resources like a connection
+// before the next attempt. It receives the zero based number of the failed
+// attempt and its error, it is executed after OnRetry and before the sleep.
+// Unlike OnRetry, if it returns an error Exec stops with that error.
+// It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) BetweenAttempts(fn func(attempt int, err error) error) RetrayableI {
+	r.betweenAttempts = fn
+	return r
+}
+
+// The OnLateResult method sets a function called with the result of every
+// attempt abandoned by Exec, because it timed out or the execution was
+// cancelled, once the function of the attempt eventually returns, for
+// example to detect the attempts that were cancelled but actually succeeded.
+// A background goroutine waits for each abandoned attempt, so fn may be
+// called after Exec returns, from another goroutine, and never if the
+// function does not return. With CancelWaitsForInFlight the result of the
+// cancelled attempt is reported before Exec returns. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) OnLateResult(fn func(err error)) RetrayableI {
+	r.onLate = fn
+	return r
+}
+
+// watchLate reports with OnLateResult the result that the abandoned attempt
+// sends to ch.
+func (r *Retrayable) watchLate(ch <-chan error) {
+	onLate := r.onLate
+	if onLate == nil {
+		return
+	}
+	go func() { onLate(attemptError(<-ch)) }()
+}
+
+// late calls the OnLateResult function with the result of an abandoned
+// attempt.
+func (r *Retrayable) late(err error) {
+	if r.onLate != nil {
+		r.onLate(attemptError(err))
+	}
+}
+
+// The CancelWaitsForInFlight method sets if Exec, when it is cancelled, waits
+// for the running attempt to return before reporting the cancellation, so the
+// resources of the attempt are released when Exec returns. The result of that
+// attempt is discarded, or reported to OnLateResult. Exec blocks until the
+// function returns, so the function must honor the cancellation (see Runner)
+// for the wait to be bounded. By default Exec returns immediately. It returns
+// a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) CancelWaitsForInFlight(wait bool) RetrayableI {
+	r.cancelWaits = wait
+	return r
+}
+
+// The Quorum method enables the quorum mode, instead of finishing on the
+// first success Exec keeps executing the function until it succeeds the
+// required number of times, using the retries as the total number of
+// attempts. Exec stops early with ErrQuorumImpossible as soon as the
+// attempts left can not reach the required successes, so requiring 3 of
+// 5 attempts the execution finishes after the third failure. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) Quorum(required int) RetrayableI {
+	r.quorum = required
+	return r
+}
+
+// The ParallelQuorum method executes all the attempts of the quorum mode at
+// the same time instead of one after the other, and cancels the context of
+// the ones still running as soon as the quorum is reached or can not be
+// reached anymore, they are counted in Stats.CancelledAttempts. There is no
+// delay between the attempts and the callbacks between them are not called,
+// but every result is classified like in sequence, so SuccessWhen,
+// TreatAsSuccess, AbortOn and RetryIf apply, an abort cancels the others, and
+// MaxInFlight bounds the attempts started at once. With a quorum of one or
+// less it returns on the first success, like hedged requests. The number of
+// attempts must be finite, RetryForever without AbsoluteMaxAttempts makes
+// Exec return ErrInvalidConfig. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) ParallelQuorum(parallel bool) RetrayableI {
+	r.parallelQuorum = parallel
+	return r
+}
+
+// quorumImpossible returns true in quorum mode when the remaining attempts
+// can not reach the required successes.
+func (r *Retrayable) quorumImpossible(stats Stats, remaining int) bool {
+	return r.quorum > 1 && stats.Successes+remaining < r.quorum
+}
+
+// The Deadline method sets a time after which the execution is abandoned
+// with ErrDeadline, even in the middle of an attempt or of the delay between
+// retries, so Exec never sleeps past the deadline. It returns a RetrayableI
+// instance, allowing method chaining.
+//
+// The delays between retries are truncated so they do not waste the time
+// left on sleeping instead of attempting. A delay is kept when the timeout of
+// the next attempt still fits after it before the deadline, else it is
+// capped so the next attempt has its whole timeout before the deadline. When
+// the time left is not longer than that timeout, or without a timeout when
+// the delay would reach the deadline, there is no time for another attempt:
+// Exec skips the sleep and returns ErrDeadline right away. The same rule
+// applies to MaxElapsed, ExecTimeout and the deadline of the context of
+// RetryContext.
+func (r *Retrayable) Deadline(deadline time.Time) RetrayableI {
+	r.deadline = deadline
+	return r
+}
+
+// The MaxElapsed method sets the maximum time an execution can take, counted
+// from the start of Exec, it works like a Deadline relative to each
+// execution and the earliest of both applies. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) MaxElapsed(maxElapsed time.Duration) RetrayableI {
+	r.maxElapsed = maxElapsed
+	return r
+}
+
+// The ExecTimeout method bounds the whole Exec like a context.WithTimeout
+// around it, so even a single attempt that hangs without a per attempt
+// timeout is abandoned once timeout passes. Exec then returns ErrDeadline,
+// which also wraps context.DeadlineExceeded. It is combined with Deadline and
+// MaxElapsed, the earliest of them applies. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) ExecTimeout(timeout time.Duration) RetrayableI {
+	r.execTimeout = timeout
+	return r
+}
+
+// The MaxTotalSleep method caps the total time an execution sleeps between
+// retries, unlike MaxElapsed the time of the attempts is not counted. The
+// delay that would exceed the cap is shortened to reach it, and once it is
+// reached the remaining retries are executed without sleeping. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) MaxTotalSleep(maxSleep time.Duration) RetrayableI {
+	r.maxTotalSleep = maxSleep
+	return r
+}
+
+// The RetryRate method allows at most maxRetries retries in any window of
+// the per duration, for example 3 per minute to not hammer a service during
+// a long outage. A retry that would exceed the rate is delayed, not dropped,
+// until the oldest retry of the window leaves it, on top of the delay of the
+// backoff. The first attempt of an execution is not limited, and the window
+// is shared by all the executions of the instance. The wait counts towards
+// the Deadline. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) RetryRate(maxRetries int, per time.Duration) RetrayableI {
+	r.rate = nil
+	if maxRetries > 0 && per > 0 {
+		r.rate = newRateWindow(maxRetries, per)
+	}
+	return r
+}
+
+// The RetryWhile method sets a predicate evaluated after every failed attempt
+// that is going to be retried, it receives the zero based number of the
+// failed attempt, the time elapsed since the start of Exec and the error.
+// When it returns false Exec stops with that error. It is an additional stop
+// condition, SetRetries, MaxElapsed and Deadline still apply. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) RetryWhile(fn func(attempt int, elapsed time.Duration, err error) bool) RetrayableI {
+	r.retryWhile = fn
+	return r
+}
+
+// The StopWhen method sets the conditions that stop the execution, they are
+// checked after every failed attempt that would be retried and Exec stops
+// with the error of that attempt as soon as ANY of them is met, like
+// AfterAttempts, AfterElapsed and AfterTimeouts. They are combined the same
+// way with the other stop settings, SetRetries, MaxElapsed, Deadline,
+// BudgetFor and RetryWhile: the first one met stops the execution. It
+// replaces the conditions of a previous call. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) StopWhen(conditions ...StopCondition) RetrayableI {
+	r.stopWhen = conditions
+	return r
+}
+
+// The StopWhenFlag method sets a shutdown flag that stops the execution once
+// it is set, without wiring a context just to observe it. The flag is polled,
+// not watched: it is checked before every attempt and every sleep, so a
+// running attempt or sleep is not interrupted. Exec then returns
+// ErrCancelled, which also wraps ErrStopFlag, with the Cancelled outcome. It
+// is combined with the other stop settings, the first one met stops the
+// execution. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) StopWhenFlag(flag *atomic.Bool) RetrayableI {
+	r.stopFlag = flag
+	return r
+}
+
+// flagged returns true if the flag of StopWhenFlag is set, and sets the error
+// and the outcome of stats for it.
+func (r *Retrayable) flagged(stats *Stats) bool {
+	if r.stopFlag == nil || !r.stopFlag.Load() {
+		return false
+	}
+	stats.Err = causeError{err: ErrCancelled, cause: ErrStopFlag}
+	stats.Outcome = OutcomeCancelled
+	return true
+}
+
+// The WithStore method sets a RetryStore to coordinate the attempts with the
+// other instances that use the same name. Before every attempt Exec reserves
+// it in the store and waits for the returned time, the wait counts towards
+// the Deadline, and after the attempt Exec records its result. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) WithStore(store RetryStore, name string) RetrayableI {
+	r.store = store
+	r.storeName = name
+	return r
+}
+
+// shouldStop returns true if any of the StopWhen conditions is met.
+func (r *Retrayable) shouldStop(stats Stats, elapsed time.Duration) bool {
+	for _, condition := range r.stopWhen {
+		if condition(stats, elapsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// budget is a max number of retries for the errors that match it.
+type budget struct {
+	matcher func(err error) bool
+	max     int
+}
+
+// The BudgetFor method adds a retry budget for the errors that match matcher,
+// those errors are retried at most max times in each execution, counted
+// independently from the other budgets. When a budget is exhausted Exec
+// stops with ErrExhausted, even if there are retries left. An error that
+// matches several budgets counts for all of them, the errors that match none
+// are only limited by SetRetries. Timeouts are matched as ErrTimeout, or the
+// error of TimeoutErrorFunc.
+// It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) BudgetFor(matcher func(err error) bool, max int) RetrayableI {
+	r.budgets = append(r.budgets, budget{matcher: matcher, max: max})
+	return r
+}
+
+// budgetExhausted counts err in the budgets it matches, it returns true if
+// any of them is exhausted.
+func (r *Retrayable) budgetExhausted(counts []int, err error) bool {
+	exhausted := false
+	for i, budget := range r.budgets {
+		if budget.matcher(err) {
+			counts[i]++
+			exhausted = exhausted || counts[i] > budget.max
+		}
+	}
+	return exhausted
+}
+
+// context returns the context of an execution started at start, it is done
+// when the execution is cancelled, one of the CancelOnSignal signals is
+// received or its deadline passes.
+func (r *Retrayable) context(start time.Time) (context.Context, context.CancelFunc) {
+	parent, stop := r.cancelContext, func() {}
+	if len(r.signals) > 0 {
+		parent, stop = signal.NotifyContext(parent, r.signals...)
+	}
+
+	deadline := r.deadline
+	for _, elapsed := range []time.Duration{r.maxElapsed, r.execTimeout} {
+		if elapsed > 0 && (deadline.IsZero() || start.Add(elapsed).Before(deadline)) {
+			deadline = start.Add(elapsed)
+		}
+	}
+	ctx, cancel := context.WithCancel(parent)
+	if !deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(parent, deadline)
+	}
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// The CancelOnSignal method cancels the execution when the process receives
+// any of the signals, like os.Interrupt for Ctrl-C in a command line tool,
+// and Exec returns ErrCancelled. The signals are handled with
+// signal.NotifyContext only while Exec runs, the handler is removed when it
+// returns. The signal handling is global to the process: while Exec runs the
+// signals do not have their default behavior, so Ctrl-C does not kill the
+// process. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) CancelOnSignal(signals ...os.Signal) RetrayableI {
+	r.signals = signals
+	return r
+}
+
+// interrupted sets the error and the outcome of an execution whose context
+// is done.
+func interrupted(ctx context.Context, stats Stats) Stats {
+	cause := ctx.Err()
+	if errors.Is(cause, context.DeadlineExceeded) {
+		stats.Err = causeError{err: ErrDeadline, cause: cause}
+		stats.Outcome = OutcomeDeadline
+		return stats
+	}
+	stats.Err = causeError{err: ErrCancelled, cause: cause}
+	stats.Outcome = OutcomeCancelled
+	return stats
+}
+
+// alreadyCancelled returns the Stats of an execution whose context was done
+// before it started, cause is the error of the context.
+func alreadyCancelled(cause error) Stats {
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return Stats{Err: causeError{err: ErrAlreadyCancelled, cause: causeError{err: ErrDeadline, cause: cause}}, Outcome: OutcomeDeadline}
+	}
+	return Stats{Err: causeError{err: ErrAlreadyCancelled, cause: cause}, Outcome: OutcomeCancelled}
+}
+
+// fitDeadline truncates the delay before the given zero based attempt to the
+// time left before the deadline of ctx, see Deadline, prev is the error of
+// the previous attempt. It returns false if there is no time left for the
+// attempt.
+func (r *Retrayable) fitDeadline(ctx context.Context, delay time.Duration, attempt int, prev error) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok || delay <= 0 {
+		return delay, true
+	}
+	timeout, overridden := r.control.peekTimeout()
+	if !overridden {
+		timeout = r.configuredTimeout(attempt, prev)
+	}
+	room := time.Until(deadline) - timeout
+	switch {
+	case delay < room:
+		return delay, true
+	case timeout > 0 && room > 0:
+		return room, true
+	}
+	return 0, false
+}
+
+// wait sleeps for the given delay, it returns false if the context is done
+// before.
+func (r *Retrayable) wait(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	if r.sleepFn != nil {
+		r.sleepFn(delay)
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// The CurrentDelay method returns the delay Exec is currently sleeping for
+// before the next retry, or zero when it is not sleeping. It is safe to call
+// it from another goroutine and it is only meaningful while Exec is running.
+func (r *Retrayable) CurrentDelay() time.Duration {
+	return time.Duration(r.currentDelay.Load())
+}
+
+// The Snapshot method returns a copy of the Stats of the running execution at
+// this point in time, updated when every attempt starts and finishes, with
+// Elapsed set to the time since Exec started. Once Exec returns it is the
+// final Stats of the last execution. It is safe to call it from another
+// goroutine.
+func (r *Retrayable) Snapshot() Stats {
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+	stats := r.snapshot
+	if r.running {
+		stats.Elapsed = time.Since(r.started)
+	}
+	return stats
+}
+
+// publish stores stats as the Snapshot of the running execution.
+func (r *Retrayable) publish(stats Stats) {
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+	r.snapshot = stats
+}
+
+// The Progress method returns a channel that receives a snapshot of the Stats
+// after every attempt of the next execution, and is closed when Exec
+// finishes, even when it returns without executing the function, like on
+// ErrInvalidConfig or ErrCircuitOpen. Call it before Exec, the channel
+// belongs to the Exec that starts after it. Sends never block: if the reader
+// is slower than the attempts, the snapshots that do not fit in the buffer
+// of the channel are dropped. The Outcome of the snapshots is not set, it is
+// only known once Exec returns.
+func (r *Retrayable) Progress() <-chan Stats {
+	r.progressMu.Lock()
+	defer r.progressMu.Unlock()
+	if r.progress == nil {
+		r.progress = make(chan Stats, 16)
+	}
+	return r.progress
+}
+
+// The CollectErrors method sets if Exec keeps the error of every failed
+// attempt in Stats.Errors, in the order they happened, a timeout is
+// ErrTimeout, and its Reason in Stats.Reasons. Stats.Err is still the error
+// of the last attempt. By default the errors are not collected. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) CollectErrors(collect bool) RetrayableI {
+	r.collectErrors = collect
+	return r
+}
+
+// The RecordAttemptTimes method sets if Exec keeps the time each attempt
+// started in Stats.AttemptTimes, as absolute timestamps to line up the
+// attempts with external logs. The times are taken from the clock of
+// WithClock. By default they are not recorded. It returns a RetrayableI
+// instance, allowing method chaining.
+func (r *Retrayable) RecordAttemptTimes(record bool) RetrayableI {
+	r.recordTimes = record
+	return r
+}
+
+// recordStart adds the current time to Stats.AttemptTimes if
+// RecordAttemptTimes is enabled.
+func (r *Retrayable) recordStart(stats *Stats) {
+	if !r.recordTimes {
+		return
+	}
+	stats.AttemptTimes = append(stats.AttemptTimes, r.clock())
+}
+
+// clock returns the current time of the clock of WithClock.
+func (r *Retrayable) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// collect adds the error of the failed attempt and its reason to Stats.Errors
+// and Stats.Reasons if CollectErrors is enabled, and counts it in
+// Stats.ErrorCounts if there is a Classify function. The counts are copied,
+// so the snapshots already sent to Progress do not change.
+func (r *Retrayable) collect(stats *Stats, reason Reason) {
+	if stats.Err == nil {
+		return
+	}
+	if r.collectErrors {
+		stats.Errors = append(stats.Errors, stats.Err)
+		stats.Reasons = append(stats.Reasons, reason)
+	}
+	if r.classify != nil {
+		category := r.classify(stats.Err)
+		if category == "" {
+			category = UNCLASSIFIED_CATEGORY
+		}
+		counts := make(map[string]int, len(stats.ErrorCounts)+1)
+		for key, count := range stats.ErrorCounts {
+			counts[key] = count
+		}
+		counts[category]++
+		stats.ErrorCounts = counts
+	}
+}
+
+// The Classify method sets a function that returns the category of the error
+// of every failed attempt, like "timeout", "network" or "server", to count
+// them in Stats.ErrorCounts. The timeouts are classified by their error. The
+// errors classified with an empty category are counted in
+// UNCLASSIFIED_CATEGORY. It returns a RetrayableI instance, allowing method
+// chaining.
+func (r *Retrayable) Classify(fn func(err error) string) RetrayableI {
+	r.classify = fn
+	return r
+}
+
+// takeProgress returns the channel of Progress for a new execution, if any.
+func (r *Retrayable) takeProgress() chan Stats {
+	r.progressMu.Lock()
+	defer r.progressMu.Unlock()
+	progress := r.progress
+	r.progress = nil
+	return progress
+}
+
+// report sends a snapshot of the stats to the progress channel, it drops the
+// snapshot if the channel is full, and publishes it for Snapshot.
+func (r *Retrayable) report(progress chan Stats, stats Stats) {
+	r.publish(stats)
+	if progress == nil {
+		return
+	}
+	select {
+	case progress <- stats:
+	default:
+	}
+}
+
+// The Lifetime method returns the cumulative stats of all the Exec calls of
+// the instance, useful for long lived shared instances. It is safe to call
+// it from another goroutine. Each counter is updated atomically when Exec
+// returns, so a read concurrent with Exec may see a partially updated value.
+func (r *Retrayable) Lifetime() LifetimeStats {
+	return r.lifetime.snapshot()
+}
+
+// The ResetLifetime method sets all the counters of Lifetime to zero.
+func (r *Retrayable) ResetLifetime() {
+	r.lifetime.reset()
+}
+
+// The Probe method sets a cheap health check that is executed before every
+// retry, while it returns false Exec waits for the interval and checks it
+// again instead of retrying the function. The time waiting for the probe
+// counts towards the Deadline, the execution is abandoned if it passes
+// before the probe succeeds. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) Probe(fn func() bool, interval time.Duration) RetrayableI {
+	r.probe = fn
+	r.probeInterval = interval
+	return r
+}
+
+// waitProbe waits until the probe succeeds, counting every failed check as a
+// skipped attempt, it returns false if the context is done before.
+func (r *Retrayable) waitProbe(ctx context.Context, stats *Stats) bool {
+	for !r.probe() {
+		stats.Skipped++
+		if !r.wait(ctx, r.probeInterval) {
+			return false
+		}
+	}
+	return true
+}
+
+// The CircuitBreakerWindow method enables a circuit breaker over the
+// executions of the instance. Once window executions ran, it trips when the
+// rate of failed executions among the last window of them reaches
+// failureRate (from 0 to 1). While it is open Exec returns ErrCircuitOpen
+// without executing the function, after the cooldown a single trial
+// execution is allowed: if it succeeds the breaker closes with an empty
+// window, otherwise it opens again. Cancelled executions are not counted,
+// and a cancelled trial leaves it open until the next trial. It returns a
+// RetrayableI instance, allowing method chaining.
+func (r *Retrayable) CircuitBreakerWindow(window int, failureRate float64, cooldown time.Duration) RetrayableI {
+	r.breaker = nil
+	if window > 0 {
+		r.breaker = newBreaker(window, failureRate, cooldown)
+	}
+	return r
+}
+
+// The WithSleepFunc method replaces time.Sleep for every delay of Exec, so a
+// simulation or a test can accumulate virtual time without waiting:
+//
+//	var slept time.Duration
+//	stats := retryable.Retry(fn).SetRetries(5).SetSleep(time.Minute).
+//		WithSleepFunc(func(d time.Duration) { slept += d }).
+//		Exec()
+//
+// Exec trusts fn to wait for the delay, a function that does not sleep
+// changes the timing of the execution. The cancellation is only checked
+// once fn returns. It returns a RetrayableI instance, allowing method
+// chaining.
+func (r *Retrayable) WithSleepFunc(fn func(d time.Duration)) RetrayableI {
+	r.sleepFn = fn
+	return r
+}
+
+// The WithTimeoutFunc method replaces time.After for the timeout of every
+// attempt, the attempt times out when the returned channel receives a value.
+// It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) WithTimeoutFunc(fn func(d time.Duration) <-chan time.Time) RetrayableI {
+	r.timeoutFn = fn
+	return r
+}
+
+// The WithClock method replaces time.Now for the times recorded in
+// Stats.AttemptTimes and the times matched by CronSchedule, for example to
+// make them deterministic in tests. The durations of the execution are still
+// measured with the real clock. It returns a RetrayableI instance, allowing
+// method chaining.
+func (r *Retrayable) WithClock(now func() time.Time) RetrayableI {
+	r.now = now
+	return r
+}
+
+// The SetRunner method sets the Runner used to execute every attempt of the
+// function. It returns a RetrayableI instance, allowing method chaining.
+func (r *Retrayable) SetRunner(runner Runner) RetrayableI {
+	r.runner = runner
+	return r
+}
+
+// The AsFunc method returns a function that executes the instance and returns
+// the final error of the execution, so it can be the function of another
+// instance, for example a fast inner policy retried in bursts by a slow
+// outer one:
+//
+//	inner := retryable.Retry(CallApi).SetRetries(3).SetSleep(100 * time.Millisecond)
+//	stats := retryable.Retry(inner.AsFunc()).SetRetries(5).SetSleep(time.Minute).Exec()
+//
+// The outer Stats only see the final error of every inner execution, each
+// outer attempt is a whole inner execution. Cancelling the outer instance
+// does not cancel the inner one, use RetryContext to nest them in that case.
+func (r *Retrayable) AsFunc() func() error {
+	return func() error {
+		return r.Exec().Err
+	}
+}
+
+// The Cancel method cancels the execution of the function. It does not return anything.
+// A cancelled instance can not be reused, any later call to Exec returns
+// ErrAlreadyCancelled without executing the function.
+func (r *Retrayable) Cancel() {
+	r.cancelFn()
+}
+
+// The GetTimeout method returns a channel that receives a value once the
+// timeout passes, or a nil channel that never receives when there is no
+// timeout. Its timer can not be stopped, Exec uses its own timers and
+// stops them as soon as every attempt finishes.
+func (r *Retrayable) GetTimeout() <-chan time.Time {
+	timeout, _ := r.timer(r.timeout)
+	return timeout
+}
+
+// timer returns a channel that receives a value once the timeout passes, and
+// a function that stops its timer so it does not linger until it fires once
+// it is not needed anymore. When the timeout is zero the channel is nil, a
+// nil channel never fires in a select, so no channel is allocated.
+func (r *Retrayable) timer(timeout time.Duration) (<-chan time.Time, func()) {
+	if timeout == 0 {
+		return nil, func() {}
+	}
+	if r.timeoutFn != nil {
+		return r.timeoutFn(timeout), func() {}
+	}
+
+	t := time.NewTimer(timeout)
+	return t.C, func() { t.Stop() }
+}
+
+// attemptTimeout returns the timeout of the given zero based attempt, prev is
+// the error of the previous one.
+func (r *Retrayable) attemptTimeout(attempt int, prev error) time.Duration {
+	if timeout, ok := r.control.takeTimeout(); ok {
+		return timeout
+	}
+	return r.configuredTimeout(attempt, prev)
+}
+
+// configuredTimeout returns the timeout of the given zero based attempt
+// without the override of the Controller, prev is the error of the previous
+// one.
+func (r *Retrayable) configuredTimeout(attempt int, prev error) time.Duration {
+	if prev != nil {
+		for _, t := range r.timeoutsFor {
+			if t.matcher(prev) {
+				return t.timeout
+			}
+		}
+	}
+	if attempt+1 < r.timeoutFrom {
+		return 0
+	}
+	if r.adaptive != nil {
+		if timeout, ok := r.adaptive.timeout(); ok {
+			return timeout
+		}
+	}
+	return r.timeout
+}
+
+// he Exec method executes the function with the specified settings and returns a 
+// Stats struct that contains the error result of the function (if any), the number 
+// of retries attempted, and the number of timeouts that occurred.
+// If the instance was already cancelled, or the context of RetryContext is
+// done, Exec returns ErrAlreadyCancelled and the function is not executed,
+// with the Deadline outcome when the deadline of the context passed.
+// A panic inside Exec, like in a Backoff, a Probe or a Fallback, is recovered
+// and reported as ErrPanic, with the attempts run before it, and counts as a
+// failure for CircuitBreakerWindow and PersistentState, but the panics of
+// OnSuccess, OnGiveUp, Finalize and Observe are not recovered. The attempts
+// of the function run in their own goroutines, so their panics are not
+// recovered by Exec.
+func (r *Retrayable) Exec() (stats Stats) {
+	r.prepareDone()
+	defer r.closeDone()
+	// every execution closes its Progress channel, even the one that returns
+	// before running the function
+	progress := r.takeProgress()
+	if progress != nil {
+		defer close(progress)
+	}
+	start := time.Now()
+	r.snapshotMu.Lock()
+	r.snapshot, r.started, r.running = Stats{}, start, true
+	r.snapshotMu.Unlock()
+	// executed is set once the breaker let the execution run, a panic after it
+	// is a failure that must still reach the breaker and the saved State
+	executed := false
+	var prior State
+	defer func() {
+		if p := recover(); p != nil {
+			if executed {
+				// the attempts published before the panic
+				r.snapshotMu.Lock()
+				stats = r.snapshot
+				r.snapshotMu.Unlock()
+			}
+			stats.Err = fmt.Errorf("%w: %v", ErrPanic, p)
+			stats.Outcome = OutcomeAborted
+		}
+		if stats.Retries < 0 {
+			stats.Retries = 0
+		}
+		if executed {
+			r.record(prior, stats)
+		}
+		if stats.Fields == nil {
+			stats.Fields = r.runFields()
+		}
+		stats.Elapsed = time.Since(start)
+		r.snapshotMu.Lock()
+		r.snapshot, r.running = stats, false
+		r.snapshotMu.Unlock()
+		r.lifetime.record(stats)
+		if stats.Err == nil && r.onSuccess != nil {
+			r.onSuccess(stats)
+		} else if stats.Err != nil && r.onGiveUp != nil {
+			r.onGiveUp(stats)
+		}
+		if r.finalize != nil {
+			r.finalize(&stats)
+		}
+		for _, observe := range r.observers {
+			observe(stats)
+		}
+	}()
+
+	if err := r.validate(); err != nil {
+		return Stats{Err: err, Outcome: OutcomeAborted}
+	}
+	if cause := r.cancelContext.Err(); cause != nil {
+		return alreadyCancelled(cause)
+	}
+	if r.breaker != nil && !r.breaker.allow() {
+		return Stats{Err: ErrCircuitOpen, Outcome: OutcomeCircuitOpen, Skipped: 1}
+	}
+
+	executed = true
+	if r.loadState != nil {
+		prior = r.loadState()
+	}
+	stats = r.exec(prior, progress)
+	if limit, _ := r.attempts(); limit > stats.Attempts && (stats.Outcome == OutcomeCancelled || stats.Outcome == OutcomeDeadline) {
+		stats.RemainingRetries = limit - stats.Attempts
+	}
+	return stats
+}
+
+// record adds the attempts of an execution to the lifetime counters, reports
+// its result to the circuit breaker and saves its State for PersistentState,
+// a panic counts as a failure.
+func (r *Retrayable) record(prior State, stats Stats) {
+	r.lifetime.attempts.Add(int64(stats.Attempts))
+	if r.breaker != nil {
+		if stats.Outcome == OutcomeCancelled {
+			r.breaker.cancel()
+		} else {
+			r.breaker.record(stats.Err != nil)
+		}
+	}
+	if r.saveState != nil && stats.Attempts > 0 {
+		r.saveState(r.nextState(prior, stats))
+	}
+}
+
+// The ExecFuture method runs Exec in a new goroutine and returns a Future of
+// its Stats, to await the result or poll it without blocking. Cancel cancels
+// the execution like for Exec, the Future then returns ErrCancelled.
+func (r *Retrayable) ExecFuture() *Future[Stats] {
+	r.prepareDone()
+	return newFuture(r.Exec)
+}
+
+// The ExecAsync method runs Exec in a new goroutine and returns a channel
+// that receives its Stats and is then closed. Done can be called right after
+// it, the returned channel belongs to the execution it started.
+func (r *Retrayable) ExecAsync() <-chan Stats {
+	r.prepareDone()
+	result := make(chan Stats, 1)
+	go func() {
+		result <- r.Exec()
+		close(result)
+	}()
+	return result
+}
+
+// The Done method returns a channel that is closed when Exec returns, success
+// or give up, after OnSuccess, OnGiveUp and Finalize, so other goroutines
+// can select on the end of the execution. The channel belongs to the
+// running execution, the one started by ExecAsync or ExecFuture, or, when
+// none is running, to the last one that returned, so it is already closed,
+// or to the first one if the instance was never executed. Every new
+// execution of the instance gets a new channel, call Done again to wait for
+// it.
+func (r *Retrayable) Done() <-chan struct{} {
+	r.doneMu.Lock()
+	defer r.doneMu.Unlock()
+	if r.done == nil {
+		r.done = make(chan struct{})
+	}
+	return r.done
+}
+
+// prepareDone creates the channel of Done for a new execution, unless there
+// is one not closed yet.
+func (r *Retrayable) prepareDone() {
+	r.doneMu.Lock()
+	defer r.doneMu.Unlock()
+	if r.done == nil || r.doneClosed {
+		r.done, r.doneClosed = make(chan struct{}), false
+	}
+}
+
+// closeDone closes the channel of Done once the execution returns.
+func (r *Retrayable) closeDone() {
+	r.doneMu.Lock()
+	defer r.doneMu.Unlock()
+	if !r.doneClosed {
+		close(r.done)
+		r.doneClosed = true
+	}
+}
+
+// exec runs the retry loop of Exec, continuing the backoff of the prior
+// State of PersistentState, and reports its snapshots to progress.
+func (r *Retrayable) exec(prior State, progress chan Stats) Stats {
+	start := time.Now()
+	ctx, cancel := r.context(start)
+	defer cancel()
+	r.control.reset()
+
+	var err error
+	stats := Stats{Retries: -1, Fields: r.runFields()}
+	limit, capped := r.attempts()
+	budgets := make([]int, len(r.budgets))
+	var totalSleep time.Duration
+	var reps repeats
+	if r.flagged(&stats) {
+		return stats
+	}
+	first := r.firstDelay()
+	if prior.LastBackoff > first {
+		first = prior.LastBackoff
+	}
+	if first > 0 && !r.wait(ctx, first) {
+		return interrupted(ctx, stats)
+	}
+	if r.warmup != nil {
+		err, ok := r.runWarmup(ctx)
+		if !ok {
+			return interrupted(ctx, stats)
+		}
+		if err != nil && r.warmupMust {
+			stats.Err = err
+			stats.Outcome = OutcomeAborted
+			return stats
+		}
+	}
+	if r.parallelQuorum {
+		if r.flagged(&stats) {
+			return stats
+		}
+		return r.execParallel(ctx, limit, progress)
+	}
+	for i := 0; limit < 0 || i < limit; i++ {
+		if r.flagged(&stats) {
+			return stats
+		}
+		if limit >= 0 && r.quorumImpossible(stats, limit-i) {
+			stats.Err = ErrQuorumImpossible
+			stats.Outcome = OutcomeQuorumImpossible
+			return stats
+		}
+		if i > 0 && r.probe != nil && !r.waitProbe(ctx, &stats) {
+			return interrupted(ctx, stats)
+		}
+		if i > 0 && r.rate != nil && !r.waitRate(ctx) {
+			return interrupted(ctx, stats)
+		}
+		if r.store != nil {
+			wait, err := r.store.Reserve(ctx, r.storeName, stats.Retries+1)
+			if err != nil {
+				stats.Err = err
+				stats.Outcome = OutcomeAborted
+				return stats
+			}
+			if !r.wait(ctx, wait) {
+				return interrupted(ctx, stats)
+			}
+		}
+		if r.group != nil && !r.group.take() {
+			stats.Err = groupBudgetError(stats.Err)
+			stats.Outcome = OutcomeExhausted
+			return stats
+		}
+		inFlight := r.inFlight
+		if inFlight != nil && !r.acquireInFlight(ctx, inFlight) {
+			return interrupted(ctx, stats)
+		}
+		limiter := r.limiter
+		if limiter != nil && limiter.Acquire(ctx, 1) != nil {
+			if inFlight != nil {
+				<-inFlight
+			}
+			return interrupted(ctx, stats)
+		}
+		ch := make(chan error, 1)
+		stats.Retries += 1
+		stats.Attempts++
+		r.recordStart(&stats)
+		r.publish(stats)
+		timeout := r.attemptTimeout(stats.Retries, stats.Err)
+		attemptCtx, cancelAttempt := r.attemptContext(ctx, stats.Retries)
+		attemptStart := time.Now()
+		go func(attempt int) {
+			if inFlight != nil {
+				defer func() { <-inFlight }()
+			}
+			if limiter != nil {
+				defer limiter.Release(1)
+			}
+			ch <- r.runner.Run(attemptCtx, func() error { return r.fn(attemptCtx, attempt) })
+		}(stats.Retries)
+
+		timedOut, done, reason := false, false, ReasonError
+		soft, stopSoft := r.timer(r.softTimeout)
+		hard, stopHard := r.timer(timeout)
+	attempt:
+		for {
+			select {
+			case err = <-ch:
+				break attempt
+			case <-soft:
+				soft = nil
+				if r.onSoftTimeout != nil {
+					r.onSoftTimeout()
+				}
+			case <-hard:
+				timedOut = true
+				break attempt
+			case <-ctx.Done():
+				if r.cancelWaits {
+					r.late(<-ch)
+				}
+				done = true
+				break attempt
+			}
+		}
+		stopSoft()
+		stopHard()
+		cancelAttempt()
+		if timedOut || done && !r.cancelWaits {
+			r.watchLate(ch)
+		}
+		elapsed := time.Since(attemptStart)
+		if stats.Attempts == 1 {
+			stats.FirstAttemptDuration = elapsed
+		} else {
+			stats.RetriesDuration += elapsed
+		}
+		if r.adaptive != nil && !timedOut && !done && attemptError(err) == nil {
+			r.adaptive.add(elapsed)
+		}
+
+		if done {
+			return interrupted(ctx, stats)
+		}
+		if r.settle != nil {
+			r.settle(stats.Retries, timedOut)
+		}
+		if r.store != nil {
+			result := attemptError(err)
+			if timedOut {
+				result = r.timeoutError(stats.Retries)
+			}
+			r.store.Record(ctx, r.storeName, result)
+		}
+		if timedOut {
+			stats.Err = r.timeoutError(stats.Retries)
+			stats.Timeout++
+			stats.TimeoutDuration += timeout
+			reason = ReasonTimeout
+			r.collect(&stats, reason)
+			r.report(progress, stats)
+			if r.abortTimeout || r.nonIdempotent {
+				stats.Outcome = OutcomeTimeout
+				return stats
+			}
+		} else {
+			var decision verdict
+			stats.Err, reason, decision = r.judge(err)
+			r.collect(&stats, reason)
+			r.report(progress, stats)
+			switch {
+			case decision == verdictStop && stats.Err == nil:
+				stats.succeeded = stats.Attempts
+				stats.Outcome = OutcomeSuccess
+				return stats
+			case decision == verdictStop, decision == verdictAbort:
+				stats.Outcome = OutcomeAborted
+				return stats
+			case decision == verdictSuccess:
+				stats.Successes++
+				stats.succeeded = stats.Attempts
+				if stats.Successes >= r.quorum {
+					stats.Outcome = OutcomeSuccess
+					return stats
+				}
+				continue
+			}
+		}
+
+		// the attempt failed, prepare the next one if there is any
+		if limit >= 0 && (i+1 >= limit || r.quorumImpossible(stats, limit-i-1)) {
+			continue
+		}
+		if r.budgetExhausted(budgets, stats.Err) {
+			capped = true
+			break
+		}
+		if r.repeated(&reps, stats.Retries, stats.Err) {
+			stats.Outcome = OutcomeAborted
+			return stats
+		}
+		if r.shouldStop(stats, time.Since(start)) {
+			break
+		}
+		if r.retryWhile != nil && !r.retryWhile(stats.Retries, time.Since(start), stats.Err) {
+			stats.Outcome = OutcomeAborted
+			return stats
+		}
+		if r.onRetry != nil {
+			r.onRetry(stats.Retries, stats.Err)
+		}
+		if r.onRetryReason != nil {
+			r.onRetryReason(stats.Retries, stats.Err, reason)
+		}
+		if r.betweenAttempts != nil {
+			if err := r.betweenAttempts(stats.Retries, stats.Err); err != nil {
+				stats.Err = err
+				stats.Outcome = OutcomeAborted
+				return stats
+			}
+		}
+		if timedOut {
+			continue
+		}
+		if r.flagged(&stats) {
+			return stats
+		}
+		delay := r.delay(prior.ConsecutiveFailures + stats.Retries)
+		if r.maxTotalSleep > 0 && delay > r.maxTotalSleep-totalSleep {
+			delay = r.maxTotalSleep - totalSleep
+		}
+		delay, fits := r.fitDeadline(ctx, delay, stats.Retries+1, stats.Err)
+		if !fits {
+			stats.Err = causeError{err: ErrDeadline, cause: context.DeadlineExceeded}
+			stats.Outcome = OutcomeDeadline
+			return stats
+		}
+		totalSleep += delay
+		r.currentDelay.Store(int64(delay))
+		slept := r.wait(ctx, delay)
+		r.currentDelay.Store(0)
+		if !slept {
+			return interrupted(ctx, stats)
+		}
+	}
+
+	if r.quorumImpossible(stats, 0) {
+		stats.Err = ErrQuorumImpossible
+		stats.Outcome = OutcomeQuorumImpossible
+		return stats
+	}
+	stats.Outcome = OutcomeExhausted
+	recovered := false
+	if r.escalate != nil && stats.Err != nil {
+		stats.Err = r.escalate(stats.Err)
+		recovered = true
+	}
+	if r.fallback != nil && stats.Err != nil {
+		stats.Err = r.fallback(stats.Err)
+		stats.UsedFallback = true
+		recovered = true
+	}
+	if stats.Err == nil {
+		stats.Outcome = OutcomeSuccess
+		return stats
+	}
+	if !recovered && r.exhaustedErr != nil {
+		stats.Err = causeError{err: r.exhaustedErr, cause: stats.Err}
+	} else if !recovered && capped {
+		stats.Err = causeError{err: ErrExhausted, cause: stats.Err}
+	}
+	return stats
+}
+
+// The function Retry is creating and returning an instance of the type RetrayableI.
+// The function takes an argument fn, which is a function that returns an error. 
+// A nil fn does not panic, Exec returns ErrNilFunc without executing it.
+func Retry(fn func() error) RetrayableI {
+	return newRetrayable(context.Background(), func(context.Context, int) error { return fn() }).requireFunc(fn == nil)
+}
+
+// The function Do executes fn with the given number of retries and sleep
+// between them and returns its Stats, it is a shortcut for the common case:
+//
+//	stats := retryable.Do(DoSomething, 3, time.Second)
+//
+// is the same as
+//
+//	stats := retryable.Retry(DoSomething).SetRetries(3).SetSleep(time.Second).Exec()
+func Do(fn func() error, retries int, sleep time.Duration) Stats {
+	return Retry(fn).SetRetries(retries).SetSleep(sleep).Exec()
+}
+
+// The function RetryContext is creating and returning an instance of the type
+// RetrayableI for a function that receives a context. The context is done
+// when the attempt finishes, times out or the execution is cancelled, either
+// with Cancel or by the parent ctx. When ctx is done Exec returns the
+// cancellation error promptly, even in the middle of an attempt.
+//
+// Pass the received context to nested retries, so cancelling the outer
+// execution also cancels the inner one:
+//
+//	outer := retryable.RetryContext(ctx, func(ctx context.Context) error {
+//		return retryable.RetryContext(ctx, CallApi).SetRetries(5).Exec().Err
+//	}).SetRetries(3)
+//
+//	go time.AfterFunc(10*time.Second, outer.Cancel) // cancels both executions
+//	stats := outer.Exec()
+func RetryContext(ctx context.Context, fn func(ctx context.Context) error) RetrayableI {
+	return newRetrayable(ctx, func(ctx context.Context, _ int) error { return fn(ctx) }).requireFunc(fn == nil)
+}
+
+// The function RetryBool is creating and returning an instance of the type
+// RetrayableI for a function that decides by itself if it must be retried.
+// Exec retries the function while it returns true, regardless of the error,
+// and stops as soon as it returns false, reporting its error (nil means the
+// function succeeded). If the function requests a retry without an error,
+// ErrRetryRequested is used as the error of the attempt.
+// The returned bool takes precedence over any other retry decision, like RetryIf.
+func RetryBool(fn func() (retry bool, err error)) RetrayableI {
+	return newRetrayable(context.Background(), func(context.Context, int) error {
+		return retryDecision(fn())
+	}).requireFunc(fn == nil)
+}
+
+// The function RetryBoolContext is creating and returning an instance of the
+// type RetrayableI for a function that receives a context and decides by
+// itself if it must be retried, like RetryBool. The context is the one of the
+// attempt, like for RetryContext, and the execution is cancelled when ctx is
+// done.
+func RetryBoolContext(ctx context.Context, fn func(ctx context.Context) (retry bool, err error)) RetrayableI {
+	return newRetrayable(ctx, func(ctx context.Context, _ int) error {
+		return retryDecision(fn(ctx))
+	}).requireFunc(fn == nil)
+}
+
+// retryDecision marks the error of an attempt of RetryBool with the decision
+// of its function.
+func retryDecision(retry bool, err error) error {
+	if !retry {
+		return stopError{err: err}
+	}
+	if err == nil {
+		return retryError{err: ErrRetryRequested}
+	}
+	return retryError{err: err}
+}
+
+// requireFunc makes Exec return ErrNilFunc when the function of the
+// constructor is nil.
+func (r *Retrayable) requireFunc(isNil bool) *Retrayable {
+	if isNil {
+		r.configErr = ErrNilFunc
+	}
+	return r
+}
+
+// newRetrayable creates a Retrayable with the default settings for a function
+// that receives the context and the zero based number of the attempt it is
+// executed for, the execution is cancelled when the parent ctx is done.
+func newRetrayable(parent context.Context, fn func(ctx context.Context, attempt int) error) *Retrayable {
+	ctx, cancel := context.WithCancel(parent)
+	return &Retrayable{
+		fn:            fn,
+		runner:        defaultRunner{},
+		retries:       1,
+		backoff:       Constant(0),
+		rnd:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		cancelContext: ctx,
+		cancelFn:      cancel,
+	}
 }
@@ -0,0 +1,1345 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoAttempts(t *testing.T) {
+	calls := 0
+	stats := Do(func() error {
+		calls++
+		return errors.New("failed")
+	}, 3, 0)
+
+	if calls != 3 || stats.Attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d calls and %d attempts", calls, stats.Attempts)
+	}
+	if stats.Retries != 2 {
+		t.Errorf("expected 2 retries, got %d", stats.Retries)
+	}
+}
+
+func TestStatsOutcomePredicates(t *testing.T) {
+	tests := []struct {
+		outcome Outcome
+		is      func(Stats) bool
+	}{
+		{OutcomeSuccess, Stats.IsSuccess},
+		{OutcomeExhausted, Stats.IsExhausted},
+		{OutcomeQuorumImpossible, Stats.IsExhausted},
+		{OutcomeCancelled, Stats.IsCancelled},
+		{OutcomeTimeout, Stats.IsTimeout},
+		{OutcomeAborted, Stats.IsAborted},
+		{OutcomeCircuitOpen, Stats.IsAborted},
+		{OutcomeDeadline, Stats.IsDeadline},
+	}
+	predicates := []func(Stats) bool{
+		Stats.IsSuccess, Stats.IsCancelled, Stats.IsTimeout,
+		Stats.IsExhausted, Stats.IsAborted, Stats.IsDeadline,
+	}
+	for _, test := range tests {
+		stats := Stats{Outcome: test.outcome}
+		if !test.is(stats) {
+			t.Errorf("%v: expected its predicate to be true", test.outcome)
+		}
+		matched := 0
+		for _, is := range predicates {
+			if is(stats) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			t.Errorf("%v: expected exactly one predicate to be true, got %d", test.outcome, matched)
+		}
+	}
+}
+
+func TestStatsReasonCode(t *testing.T) {
+	tests := []struct {
+		outcome Outcome
+		code    string
+	}{
+		{OutcomeSuccess, "success"},
+		{OutcomeExhausted, "exhausted"},
+		{OutcomeQuorumImpossible, "exhausted"},
+		{OutcomeCancelled, "cancelled"},
+		{OutcomeTimeout, "timeout"},
+		{OutcomeAborted, "aborted"},
+		{OutcomeCircuitOpen, "aborted"},
+		{OutcomeDeadline, "deadline"},
+		{Outcome(100), "aborted"},
+	}
+	for _, test := range tests {
+		if code := (Stats{Outcome: test.outcome}).ReasonCode(); code != test.code {
+			t.Errorf("%v: expected %q, got %q", test.outcome, test.code, code)
+		}
+	}
+}
+
+func TestStatsIsTimeoutUnlikeTimedOut(t *testing.T) {
+	stats := Stats{Outcome: OutcomeSuccess, Timeout: 1}
+	if !stats.TimedOut() || stats.IsTimeout() {
+		t.Errorf("expected TimedOut without IsTimeout, got %v and %v", stats.TimedOut(), stats.IsTimeout())
+	}
+}
+
+func TestStatsTimedOut(t *testing.T) {
+	if (Stats{}).TimedOut() {
+		t.Error("expected TimedOut to be false without timeouts")
+	}
+	if stats := Retry(func() error { return errors.New("failed") }).SetRetries(2).Exec(); stats.TimedOut() {
+		t.Error("expected TimedOut to be false when the attempts fail without timing out")
+	}
+}
+
+func TestWarmupHonorsExecTimeout(t *testing.T) {
+	calls := 0
+	start := time.Now()
+	stats := Retry(func() error {
+		calls++
+		return nil
+	}).Warmup(func() error {
+		time.Sleep(300 * time.Millisecond)
+		return nil
+	}).ExecTimeout(50 * time.Millisecond).Exec()
+
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("expected Exec to return at the deadline, took %v", elapsed)
+	}
+	if !errors.Is(stats.Err, ErrDeadline) || stats.Outcome != OutcomeDeadline {
+		t.Errorf("expected %v, got %v (%v)", ErrDeadline, stats.Err, stats.Outcome)
+	}
+	if calls != 0 || stats.Attempts != 0 {
+		t.Errorf("expected no attempt, got %d", calls)
+	}
+}
+
+func TestWarmupMustSucceed(t *testing.T) {
+	errWarmup := errors.New("warmup")
+	calls, warmups := 0, 0
+	rt := Retry(func() error {
+		calls++
+		return nil
+	}).Warmup(func() error {
+		warmups++
+		return errWarmup
+	})
+
+	if stats := rt.Exec(); stats.Err != nil || calls != 1 || stats.Attempts != 1 {
+		t.Errorf("expected the warmup error to be ignored, got %v after %d calls", stats.Err, calls)
+	}
+	stats := rt.WarmupMustSucceed(true).Exec()
+	if !errors.Is(stats.Err, errWarmup) || stats.Outcome != OutcomeAborted {
+		t.Errorf("expected %v, got %v (%v)", errWarmup, stats.Err, stats.Outcome)
+	}
+	if calls != 1 || warmups != 2 {
+		t.Errorf("expected 1 call and 2 warmups, got %d and %d", calls, warmups)
+	}
+}
+
+func TestWithFieldsReachTheHooks(t *testing.T) {
+	fields := map[string]any{"request_id": "abc"}
+	var retried, gaveUp, observed any
+	var rt RetrayableI
+	rt = Retry(func() error { return errors.New("failed") }).
+		SetRetries(2).WithFields(fields).
+		OnRetry(func(int, error) { retried = rt.Snapshot().Fields["request_id"] }).
+		OnGiveUp(func(stats Stats) { gaveUp = stats.Fields["request_id"] }).
+		Observe(func(stats Stats) { observed = stats.Fields["request_id"] })
+
+	stats := rt.Exec()
+	fields["request_id"] = "changed"
+	if stats.Fields["request_id"] != "abc" {
+		t.Errorf("expected the fields copied at the start, got %v", stats.Fields)
+	}
+	if retried != "abc" || gaveUp != "abc" || observed != "abc" {
+		t.Errorf("expected the fields in every hook, got %v, %v and %v", retried, gaveUp, observed)
+	}
+}
+
+func TestExecAlreadyCancelled(t *testing.T) {
+	calls := 0
+	rt := Retry(func() error {
+		calls++
+		return nil
+	})
+	rt.Cancel()
+	stats := rt.Exec()
+	if !errors.Is(stats.Err, ErrAlreadyCancelled) || stats.Outcome != OutcomeCancelled {
+		t.Errorf("expected %v, got %v (%v)", ErrAlreadyCancelled, stats.Err, stats.Outcome)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stats = RetryContext(ctx, func(context.Context) error {
+		calls++
+		return nil
+	}).Exec()
+	if !errors.Is(stats.Err, ErrAlreadyCancelled) || !errors.Is(stats.Err, context.Canceled) || stats.Outcome != OutcomeCancelled {
+		t.Errorf("expected %v wrapping %v, got %v (%v)", ErrAlreadyCancelled, context.Canceled, stats.Err, stats.Outcome)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	stats = RetryContext(ctx, func(context.Context) error {
+		calls++
+		return nil
+	}).Exec()
+	if !errors.Is(stats.Err, ErrAlreadyCancelled) || !errors.Is(stats.Err, ErrDeadline) || !errors.Is(stats.Err, context.DeadlineExceeded) {
+		t.Errorf("expected %v wrapping %v and %v, got %v", ErrAlreadyCancelled, ErrDeadline, context.DeadlineExceeded, stats.Err)
+	}
+	if stats.Outcome != OutcomeDeadline {
+		t.Errorf("expected outcome %v, got %v", OutcomeDeadline, stats.Outcome)
+	}
+	if calls != 0 {
+		t.Errorf("expected the function not to be executed, got %d calls", calls)
+	}
+}
+
+func TestAbortOnTimeout(t *testing.T) {
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}).SetRetries(3).SetTimeout(5 * time.Millisecond).AbortOnTimeout(true).Exec()
+
+	if !errors.Is(stats.Err, ErrTimeout) || stats.Outcome != OutcomeTimeout {
+		t.Errorf("expected %v, got %v (%v)", ErrTimeout, stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 1 || stats.Timeout != 1 {
+		t.Errorf("expected 1 timed out attempt, got %d attempts and %d timeouts", stats.Attempts, stats.Timeout)
+	}
+}
+
+func TestTimeoutRetriedByDefault(t *testing.T) {
+	stats := Retry(func() error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}).SetRetries(3).SetTimeout(5 * time.Millisecond).Exec()
+
+	if !errors.Is(stats.Err, ErrTimeout) || stats.Outcome != OutcomeExhausted {
+		t.Errorf("expected %v, got %v (%v)", ErrTimeout, stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 3 || stats.Timeout != 3 {
+		t.Errorf("expected 3 timed out attempts, got %d attempts and %d timeouts", stats.Attempts, stats.Timeout)
+	}
+}
+
+func TestMaxInFlightBoundsTimedOutAttempts(t *testing.T) {
+	var inFlight gauge
+	release := make(chan struct{})
+	var once sync.Once
+	limited := 0
+	stats := Retry(func() error {
+		defer inFlight.enter()()
+		<-release
+		return errors.New("failed")
+	}).SetRetries(5).SetTimeout(time.Millisecond).MaxInFlight(2, func() {
+		limited++
+		once.Do(func() { time.AfterFunc(10*time.Millisecond, func() { close(release) }) })
+	}).Exec()
+
+	if stats.Attempts != 5 {
+		t.Errorf("expected 5 attempts, got %d", stats.Attempts)
+	}
+	if inFlight.peak.Load() > 2 {
+		t.Errorf("expected at most 2 attempts running, got %d", inFlight.peak.Load())
+	}
+	if limited == 0 {
+		t.Error("expected onLimit to be called")
+	}
+}
+
+func TestTimeoutDuration(t *testing.T) {
+	var calls atomic.Int32
+	stats := Retry(func() error {
+		if calls.Add(1) < 3 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return nil
+	}).SetRetries(3).SetTimeout(5 * time.Millisecond).Exec()
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if stats.Timeout != 2 || stats.TimeoutDuration != 10*time.Millisecond {
+		t.Errorf("expected 2 timeouts lasting 10ms, got %d lasting %v", stats.Timeout, stats.TimeoutDuration)
+	}
+	if !stats.TimedOut() {
+		t.Error("expected TimedOut to be true")
+	}
+}
+
+func TestCancelInterruptsTheSleep(t *testing.T) {
+	rt := Retry(func() error { return errors.New("failed") }).SetRetries(3).SetSleep(time.Hour)
+	time.AfterFunc(20*time.Millisecond, rt.Cancel)
+
+	start := time.Now()
+	stats := rt.Exec()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Cancel to interrupt the sleep, took %v", elapsed)
+	}
+	if !errors.Is(stats.Err, ErrCancelled) || stats.Outcome != OutcomeCancelled {
+		t.Errorf("expected %v, got %v (%v)", ErrCancelled, stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", stats.Attempts)
+	}
+}
+
+func TestDeadlineInterruptsTheSleep(t *testing.T) {
+	start := time.Now()
+	stats := Retry(func() error { return errors.New("failed") }).
+		SetRetries(3).SetSleep(time.Hour).Deadline(start.Add(30 * time.Millisecond)).Exec()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the deadline to interrupt the sleep, took %v", elapsed)
+	}
+	if !errors.Is(stats.Err, ErrDeadline) || stats.Outcome != OutcomeDeadline {
+		t.Errorf("expected %v, got %v (%v)", ErrDeadline, stats.Err, stats.Outcome)
+	}
+}
+
+func TestNotIdempotentTimeoutNotRetried(t *testing.T) {
+	stats := Retry(func() error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}).SetRetries(3).SetTimeout(5 * time.Millisecond).Idempotent(false).Exec()
+
+	if !errors.Is(stats.Err, ErrTimeout) || stats.Outcome != OutcomeTimeout {
+		t.Errorf("expected %v, got %v (%v)", ErrTimeout, stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", stats.Attempts)
+	}
+}
+
+func TestNotIdempotentErrorRetried(t *testing.T) {
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return errors.New("failed")
+	}).SetRetries(3).Idempotent(false).Exec()
+
+	if calls != 3 || stats.Outcome != OutcomeExhausted {
+		t.Errorf("expected 3 attempts, got %d (%v)", calls, stats.Outcome)
+	}
+}
+
+func TestRetryContextReturnsWhenCtxIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	cancelled := make(chan struct{})
+
+	start := time.Now()
+	stats := RetryContext(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		time.Sleep(time.Second)
+		return ctx.Err()
+	}).SetRetries(3).Exec()
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected Exec to return during the attempt, took %v", elapsed)
+	}
+	if !errors.Is(stats.Err, ErrCancelled) {
+		t.Errorf("expected %v, got %v", ErrCancelled, stats.Err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the context of the attempt to be done")
+	}
+}
+
+func TestCancelledErrorWrapsTheContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	stats := RetryContext(ctx, func(context.Context) error { return errors.New("failed") }).
+		SetRetries(3).SetSleep(time.Hour).Exec()
+
+	if !errors.Is(stats.Err, ErrCancelled) || !errors.Is(stats.Err, context.Canceled) {
+		t.Errorf("expected %v wrapping %v, got %v", ErrCancelled, context.Canceled, stats.Err)
+	}
+
+	ctx, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	stats = RetryContext(ctx, func(context.Context) error { return errors.New("failed") }).
+		SetRetries(3).SetSleep(time.Hour).Exec()
+
+	if !errors.Is(stats.Err, ErrDeadline) || !errors.Is(stats.Err, context.DeadlineExceeded) {
+		t.Errorf("expected %v wrapping %v, got %v", ErrDeadline, context.DeadlineExceeded, stats.Err)
+	}
+	if stats.Outcome != OutcomeDeadline {
+		t.Errorf("expected outcome %v, got %v", OutcomeDeadline, stats.Outcome)
+	}
+}
+
+func TestRetryForever(t *testing.T) {
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		if calls < 20 {
+			return errors.New("failed")
+		}
+		return nil
+	}).SetRetries(2).RetryForever().Exec()
+
+	if stats.Err != nil || calls != 20 {
+		t.Errorf("expected a success after 20 attempts, got %v after %d", stats.Err, calls)
+	}
+}
+
+func TestAbsoluteMaxAttempts(t *testing.T) {
+	errFailed := errors.New("failed")
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return errFailed
+	}).RetryForever().AbsoluteMaxAttempts(4).Exec()
+
+	if calls != 4 || stats.Attempts != 4 {
+		t.Errorf("expected 4 attempts, got %d", calls)
+	}
+	if !errors.Is(stats.Err, ErrExhausted) || !errors.Is(stats.Err, errFailed) {
+		t.Errorf("expected %v wrapping %v, got %v", ErrExhausted, errFailed, stats.Err)
+	}
+
+	calls = 0
+	Retry(func() error {
+		calls++
+		return errFailed
+	}).SetRetries(2).AbsoluteMaxAttempts(4).Exec()
+	if calls != 2 {
+		t.Errorf("expected the smaller limit of 2 attempts, got %d", calls)
+	}
+}
+
+func TestTimeoutFromAttempt(t *testing.T) {
+	var calls atomic.Int32
+	stats := Retry(func() error {
+		calls.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		return errors.New("failed")
+	}).SetRetries(3).TimeoutFromAttempt(2, 5*time.Millisecond).Exec()
+
+	if stats.Attempts != 3 || stats.Timeout != 2 {
+		t.Errorf("expected the first of 3 attempts without timeout, got %d timeouts in %d attempts", stats.Timeout, stats.Attempts)
+	}
+	if !errors.Is(stats.Err, ErrTimeout) {
+		t.Errorf("expected %v, got %v", ErrTimeout, stats.Err)
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	errFailed, errEscalated := errors.New("failed"), errors.New("escalated")
+	var received error
+	stats := Retry(func() error { return errFailed }).SetRetries(2).
+		Escalate(func(lastErr error) error {
+			received = lastErr
+			return nil
+		}).Exec()
+
+	if stats.Err != nil || stats.Outcome != OutcomeSuccess || stats.UsedFallback {
+		t.Errorf("expected a true success, got %v (%v)", stats.Err, stats.Outcome)
+	}
+	if received != errFailed {
+		t.Errorf("expected the last error, got %v", received)
+	}
+
+	fallback := false
+	stats = Retry(func() error { return errFailed }).SetRetries(2).
+		Escalate(func(error) error { return errEscalated }).
+		Fallback(func(lastErr error) error {
+			fallback = true
+			if lastErr != errEscalated {
+				t.Errorf("expected the error of the escalation, got %v", lastErr)
+			}
+			return lastErr
+		}).Exec()
+	if !fallback || !errors.Is(stats.Err, errEscalated) {
+		t.Errorf("expected the fallback after the failed escalation, got %v", stats.Err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	rt := Retry(func() error { return errors.New("failed") }).SetRetries(3)
+	progress := rt.Progress()
+	rt.Exec()
+
+	attempts := 0
+	for stats := range progress {
+		attempts++
+		if stats.Attempts != attempts || stats.Err == nil {
+			t.Errorf("expected the snapshot of the attempt %d, got %+v", attempts, stats)
+		}
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 snapshots, got %d", attempts)
+	}
+}
+
+func TestProgressClosedOnEarlyReturn(t *testing.T) {
+	rt := Retry(func() error { return nil }).SetBackoff(ExponentialToMax(time.Second, 0))
+	progress := rt.Progress()
+	if stats := rt.Exec(); !errors.Is(stats.Err, ErrInvalidConfig) {
+		t.Fatalf("expected %v, got %v", ErrInvalidConfig, stats.Err)
+	}
+
+	select {
+	case _, ok := <-progress:
+		if ok {
+			t.Error("expected no snapshot without attempts")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the channel to be closed")
+	}
+	if next := rt.Progress(); next == progress {
+		t.Error("expected the next execution to get a new channel")
+	}
+}
+
+func TestExhaustedError(t *testing.T) {
+	errFailed, errGaveUp := errors.New("failed"), errors.New("gave up")
+	stats := Retry(func() error { return errFailed }).SetRetries(2).ExhaustedError(errGaveUp).Exec()
+
+	if !errors.Is(stats.Err, errGaveUp) || !errors.Is(stats.Err, errFailed) {
+		t.Errorf("expected %v wrapping %v, got %v", errGaveUp, errFailed, stats.Err)
+	}
+	if errors.Is(stats.Err, ErrExhausted) {
+		t.Errorf("expected the custom error to replace %v", ErrExhausted)
+	}
+
+	stats = Retry(func() error { return errFailed }).SetRetries(2).Exec()
+	if stats.Err != errFailed {
+		t.Errorf("expected the error of the last attempt by default, got %v", stats.Err)
+	}
+}
+
+func TestFinalHooks(t *testing.T) {
+	errSecret := errors.New("secret")
+	var succeeded, gaveUp []Stats
+	rt := Retry(func() error { return errSecret }).SetRetries(2).
+		OnSuccess(func(stats Stats) { succeeded = append(succeeded, stats) }).
+		OnGiveUp(func(stats Stats) { gaveUp = append(gaveUp, stats) }).
+		Finalize(func(stats *Stats) {
+			if stats.Err != nil {
+				stats.Err = errors.New("redacted")
+			}
+		})
+
+	stats := rt.Exec()
+	if stats.Err == nil || stats.Err.Error() != "redacted" {
+		t.Errorf("expected Finalize to redact the error, got %v", stats.Err)
+	}
+	if len(gaveUp) != 1 || gaveUp[0].Err != errSecret || len(succeeded) != 0 {
+		t.Errorf("expected OnGiveUp to see the Stats before Finalize, got %v", gaveUp)
+	}
+
+	Retry(func() error { return nil }).
+		OnSuccess(func(stats Stats) { succeeded = append(succeeded, stats) }).
+		OnGiveUp(func(stats Stats) { gaveUp = append(gaveUp, stats) }).Exec()
+	if len(succeeded) != 1 || len(gaveUp) != 1 {
+		t.Errorf("expected only OnSuccess, got %d and %d calls", len(succeeded), len(gaveUp))
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	start := time.Now()
+	stats := Retry(func() error {
+		time.Sleep(time.Second)
+		return nil
+	}).ExecTimeout(20 * time.Millisecond).Exec()
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the hanging attempt to be abandoned, took %v", elapsed)
+	}
+	if !errors.Is(stats.Err, ErrDeadline) || !errors.Is(stats.Err, context.DeadlineExceeded) {
+		t.Errorf("expected %v wrapping %v, got %v", ErrDeadline, context.DeadlineExceeded, stats.Err)
+	}
+}
+
+func TestSkippedByTheProbe(t *testing.T) {
+	checks := 0
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("failed")
+		}
+		return nil
+	}).SetRetries(3).Probe(func() bool {
+		checks++
+		return checks > 2
+	}, time.Millisecond).Exec()
+
+	if stats.Err != nil || stats.Attempts != 2 {
+		t.Fatalf("expected a success after 2 attempts, got %v after %d", stats.Err, stats.Attempts)
+	}
+	if stats.Skipped != 2 {
+		t.Errorf("expected 2 skipped attempts, got %d", stats.Skipped)
+	}
+}
+
+func TestRemainingRetries(t *testing.T) {
+	stats := Retry(func() error { return errors.New("failed") }).
+		SetRetries(5).SetSleep(10 * time.Millisecond).ExecTimeout(15 * time.Millisecond).Exec()
+
+	if stats.Outcome != OutcomeDeadline {
+		t.Fatalf("expected outcome %v, got %v", OutcomeDeadline, stats.Outcome)
+	}
+	if stats.RemainingRetries != 5-stats.Attempts || stats.RemainingRetries == 0 {
+		t.Errorf("expected the attempts left of 5 after %d, got %d", stats.Attempts, stats.RemainingRetries)
+	}
+
+	stats = Retry(func() error { return errors.New("failed") }).SetRetries(2).Exec()
+	if stats.RemainingRetries != 0 {
+		t.Errorf("expected no remaining retries once exhausted, got %d", stats.RemainingRetries)
+	}
+}
+
+// codeError is an error created on the fly that errors.Is can not match.
+// gauge counts the calls running at the same time and keeps the highest
+// count.
+type gauge struct {
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+// enter records the start of a call, the returned function its end.
+func (g *gauge) enter() func() {
+	n := g.running.Add(1)
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return func() { g.running.Add(-1) }
+}
+
+type codeError struct {
+	code int
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("code %d", e.code)
+}
+
+// hasCode returns a matcher of the codeErrors with code.
+func hasCode(code int) func(err error) bool {
+	return func(err error) bool {
+		var coded *codeError
+		return errors.As(err, &coded) && coded.code == code
+	}
+}
+
+func TestTreatAsSuccessFunc(t *testing.T) {
+	stats := Retry(func() error { return &codeError{code: 409} }).
+		SetRetries(3).TreatAsSuccessFunc(hasCode(409)).Exec()
+
+	if stats.Err != nil || stats.Attempts != 1 {
+		t.Errorf("expected a success on the first attempt, got %v after %d", stats.Err, stats.Attempts)
+	}
+}
+
+func TestAbortOnFunc(t *testing.T) {
+	errOther := errors.New("other")
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		if calls == 1 {
+			return errOther
+		}
+		return &codeError{code: 400}
+	}).SetRetries(5).AbortOn(errors.New("never")).AbortOnFunc(hasCode(400)).Exec()
+
+	if stats.Outcome != OutcomeAborted || stats.Attempts != 2 {
+		t.Errorf("expected to abort on the second attempt, got %v after %d", stats.Outcome, stats.Attempts)
+	}
+}
+
+func TestSnapshotWhileRunning(t *testing.T) {
+	var calls atomic.Int32
+	rt := Retry(func() error {
+		if calls.Add(1)%2 == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return errors.New("failed")
+	}).SetRetries(10).SetSleep(time.Millisecond).SetTimeout(2 * time.Millisecond).CollectErrors(true)
+
+	done := make(chan Stats)
+	go func() { done <- rt.Exec() }()
+
+	last := 0
+	for {
+		select {
+		case stats := <-done:
+			if snapshot := rt.Snapshot(); snapshot.Attempts != stats.Attempts || snapshot.Retries != stats.Retries {
+				t.Errorf("expected the final stats, got %d attempts instead of %d", snapshot.Attempts, stats.Attempts)
+			}
+			return
+		default:
+			snapshot := rt.Snapshot()
+			if snapshot.Attempts < last {
+				t.Fatalf("expected the attempts not to decrease, got %d after %d", snapshot.Attempts, last)
+			}
+			if len(snapshot.Errors) > snapshot.Attempts {
+				t.Fatalf("expected at most an error per attempt, got %d in %d", len(snapshot.Errors), snapshot.Attempts)
+			}
+			last = snapshot.Attempts
+		}
+	}
+}
+
+func TestNilFunc(t *testing.T) {
+	group := NewGroup(context.Background())
+	tests := map[string]RetrayableI{
+		"Retry":            Retry(nil),
+		"RetryContext":     RetryContext(context.Background(), nil),
+		"RetryBool":        RetryBool(nil),
+		"RetryBoolContext": RetryBoolContext(context.Background(), nil),
+		"RetryControlled":  RetryControlled(context.Background(), nil),
+		"Group.Retry":      group.Retry(nil),
+	}
+	for name, rt := range tests {
+		stats := rt.SetRetries(3).Exec()
+		if !errors.Is(stats.Err, ErrNilFunc) || stats.Attempts != 0 {
+			t.Errorf("%s: expected %v without attempts, got %v after %d attempts", name, ErrNilFunc, stats.Err, stats.Attempts)
+		}
+	}
+
+	if stats := RetryValue[int](nil).Exec(); !errors.Is(stats.Err, ErrNilFunc) {
+		t.Errorf("RetryValue: expected %v, got %v", ErrNilFunc, stats.Err)
+	}
+}
+
+func TestAsFuncComposition(t *testing.T) {
+	calls := 0
+	inner := Retry(func() error {
+		calls++
+		if calls < 5 {
+			return fmt.Errorf("attempt %d failed", calls)
+		}
+		return nil
+	}).SetRetries(2)
+
+	stats := Retry(inner.AsFunc()).SetRetries(3).Exec()
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if calls != 5 {
+		t.Errorf("expected 5 inner attempts, got %d", calls)
+	}
+	if stats.Attempts != 3 {
+		t.Errorf("expected 3 outer attempts, got %d", stats.Attempts)
+	}
+
+	calls = 0
+	stats = Retry(inner.AsFunc()).SetRetries(2).Exec()
+	if stats.Err == nil || stats.Err.Error() != "attempt 4 failed" {
+		t.Errorf("expected the final error of the inner execution, got %v", stats.Err)
+	}
+}
+
+func TestCancelOnSignal(t *testing.T) {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Skipf("cannot find the process: %v", err)
+	}
+	var signalErr error
+	stats := RetryContext(context.Background(), func(ctx context.Context) error {
+		if signalErr = process.Signal(os.Interrupt); signalErr != nil {
+			return signalErr
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.New("not cancelled")
+		}
+	}).SetRetries(3).CancelOnSignal(os.Interrupt).Exec()
+
+	if signalErr != nil {
+		t.Skipf("cannot send the signal: %v", signalErr)
+	}
+	if !errors.Is(stats.Err, ErrCancelled) || stats.Outcome != OutcomeCancelled {
+		t.Errorf("expected %v, got %v (%v)", ErrCancelled, stats.Err, stats.Outcome)
+	}
+	if stats.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", stats.Attempts)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	errNetwork := errors.New("connection refused")
+	errServer := errors.New("internal server error")
+	errUnknown := errors.New("unknown")
+	failures := []error{errNetwork, errServer, errNetwork, nil, errUnknown}
+	var calls atomic.Int32
+	stats := Retry(func() error {
+		n := calls.Add(1)
+		if n == 4 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return failures[n-1]
+	}).SetRetries(5).SetTimeout(5 * time.Millisecond).Classify(func(err error) string {
+		switch {
+		case errors.Is(err, ErrTimeout):
+			return "timeout"
+		case errors.Is(err, errNetwork):
+			return "network"
+		case errors.Is(err, errServer):
+			return "server"
+		}
+		return ""
+	}).Exec()
+
+	expected := map[string]int{"network": 2, "server": 1, "timeout": 1, UNCLASSIFIED_CATEGORY: 1}
+	if len(stats.ErrorCounts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, stats.ErrorCounts)
+	}
+	for category, count := range expected {
+		if stats.ErrorCounts[category] != count {
+			t.Errorf("expected %v, got %v", expected, stats.ErrorCounts)
+			break
+		}
+	}
+}
+
+type attemptTimeoutError struct {
+	attempt int
+}
+
+func (e attemptTimeoutError) Error() string {
+	return fmt.Sprintf("attempt %d timed out", e.attempt)
+}
+
+func TestTimeoutErrorFunc(t *testing.T) {
+	var attempts []int
+	stats := Retry(func() error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}).SetRetries(2).SetTimeout(time.Millisecond).CollectErrors(true).TimeoutErrorFunc(func(attempt int) error {
+		attempts = append(attempts, attempt)
+		return attemptTimeoutError{attempt: attempt}
+	}).Exec()
+
+	var timeoutErr attemptTimeoutError
+	if !errors.As(stats.Err, &timeoutErr) || timeoutErr.attempt != 1 {
+		t.Fatalf("expected the timeout error of the attempt 1, got %v", stats.Err)
+	}
+	if errors.Is(stats.Err, ErrTimeout) {
+		t.Error("expected the error not to wrap ErrTimeout")
+	}
+	if stats.Timeout != 2 || len(stats.Errors) != 2 || !errors.As(stats.Errors[0], &timeoutErr) || timeoutErr.attempt != 0 {
+		t.Errorf("expected 2 timeouts with their errors, got %d and %v", stats.Timeout, stats.Errors)
+	}
+	if len(attempts) != 2 || attempts[0] != 0 || attempts[1] != 1 {
+		t.Errorf("expected the errors of the attempts 0 and 1, got %v", attempts)
+	}
+}
+
+func TestRecordAttemptTimes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := 0
+	clock := func() time.Time {
+		ticks++
+		return start.Add(time.Duration(ticks) * time.Second)
+	}
+	failing := func() error { return errors.New("failed") }
+
+	stats := Retry(failing).SetRetries(3).WithClock(clock).RecordAttemptTimes(true).Exec()
+	if len(stats.AttemptTimes) != 3 {
+		t.Fatalf("expected 3 attempt times, got %v", stats.AttemptTimes)
+	}
+	for i, at := range stats.AttemptTimes {
+		if expected := start.Add(time.Duration(i+1) * time.Second); !at.Equal(expected) {
+			t.Errorf("expected the attempt %d to start at %v, got %v", i, expected, at)
+		}
+	}
+
+	if stats := Retry(failing).SetRetries(3).Exec(); stats.AttemptTimes != nil {
+		t.Errorf("expected no attempt times by default, got %v", stats.AttemptTimes)
+	}
+}
+
+func TestOnLateResultAfterTimeout(t *testing.T) {
+	late := make(chan error, 2)
+	var calls atomic.Int32
+	stats := Retry(func() error {
+		if calls.Add(1) == 1 {
+			time.Sleep(30 * time.Millisecond)
+			return nil
+		}
+		return errors.New("failed")
+	}).SetRetries(2).SetTimeout(5 * time.Millisecond).OnLateResult(func(err error) { late <- err }).Exec()
+
+	if stats.Err == nil {
+		t.Fatal("expected an error")
+	}
+	select {
+	case err := <-late:
+		if err != nil {
+			t.Errorf("expected the late success of the timed out attempt, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the late result to be reported")
+	}
+}
+
+func TestOnLateResultWithCancelWaitsForInFlight(t *testing.T) {
+	var late []error
+	started := make(chan struct{})
+	rt := Retry(func() error {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}).SetRetries(3).CancelWaitsForInFlight(true).OnLateResult(func(err error) { late = append(late, err) })
+	go func() {
+		<-started
+		rt.Cancel()
+	}()
+
+	stats := rt.Exec()
+	if !errors.Is(stats.Err, ErrCancelled) {
+		t.Fatalf("expected %v, got %v", ErrCancelled, stats.Err)
+	}
+	if len(late) != 1 || late[0] != nil {
+		t.Errorf("expected the late success to be reported before Exec returns, got %v", late)
+	}
+}
+
+func TestAbortOnRepeated(t *testing.T) {
+	messages := []string{"a", "b", "b", "c", "c", "c", "d"}
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return errors.New(messages[calls-1])
+	}).SetRetries(len(messages)).AbortOnRepeated(3).Exec()
+
+	if stats.Outcome != OutcomeAborted || stats.Err == nil || stats.Err.Error() != "c" {
+		t.Errorf("expected the repeated error with the aborted outcome, got %v (%v)", stats.Err, stats.Outcome)
+	}
+	if calls != 6 {
+		t.Errorf("expected to stop at the third consecutive c, got %d attempts", calls)
+	}
+}
+
+func TestAbortOnRepeatedFunc(t *testing.T) {
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return &codeError{code: 500 + calls}
+	}).SetRetries(5).AbortOnRepeatedFunc(2, func(prev, err error) bool {
+		var prevCode, code *codeError
+		return errors.As(prev, &prevCode) && errors.As(err, &code) && prevCode.code/100 == code.code/100
+	}).Exec()
+
+	if stats.Outcome != OutcomeAborted || calls != 2 {
+		t.Errorf("expected to stop after 2 errors of the same class, got %v after %d attempts", stats.Outcome, calls)
+	}
+}
+
+func TestWithConcurrencyLimit(t *testing.T) {
+	limiter := ChanLimiter(make(chan struct{}, 2))
+	var inFlight gauge
+	fn := func() error {
+		defer inFlight.enter()()
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("failed")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Retry(fn).SetRetries(2).WithConcurrencyLimit(limiter).Exec()
+		}()
+	}
+	wg.Wait()
+
+	if inFlight.peak.Load() > 2 {
+		t.Errorf("expected at most 2 attempts running across the instances, got %d", inFlight.peak.Load())
+	}
+}
+
+func TestWithConcurrencyLimitCancelled(t *testing.T) {
+	ch := make(chan struct{}, 1)
+	ch <- struct{}{}
+	calls := 0
+	rt := Retry(func() error { calls++; return nil }).WithConcurrencyLimit(ChanLimiter(ch))
+	time.AfterFunc(10*time.Millisecond, rt.Cancel)
+
+	if stats := rt.Exec(); !errors.Is(stats.Err, ErrCancelled) || calls != 0 {
+		t.Errorf("expected %v without attempts while the limiter is full, got %v after %d", ErrCancelled, stats.Err, calls)
+	}
+}
+
+func TestOnRetryReason(t *testing.T) {
+	var calls atomic.Int32
+	var reasons []Reason
+	stats := Retry(func() error {
+		switch calls.Add(1) {
+		case 1:
+			return errors.New("failed")
+		case 2:
+			time.Sleep(20 * time.Millisecond)
+		}
+		return nil
+	}).SetRetries(5).SetTimeout(5 * time.Millisecond).CollectErrors(true).
+		SuccessWhen(func(err error) bool { return err == nil && calls.Load() >= 4 }).
+		OnRetryReason(func(attempt int, err error, reason Reason) { reasons = append(reasons, reason) }).Exec()
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	expected := []Reason{ReasonError, ReasonTimeout, ReasonUnsatisfied}
+	if len(reasons) != len(expected) || len(stats.Reasons) != len(expected) {
+		t.Fatalf("expected %v, got %v and %v", expected, reasons, stats.Reasons)
+	}
+	for i := range expected {
+		if reasons[i] != expected[i] || stats.Reasons[i] != expected[i] {
+			t.Errorf("expected %v, got %v and %v", expected, reasons, stats.Reasons)
+			break
+		}
+	}
+}
+
+func TestOnRetryReasonRetryRequested(t *testing.T) {
+	var reasons []Reason
+	calls := 0
+	RetryBool(func() (bool, error) {
+		calls++
+		return calls < 2, nil
+	}).SetRetries(3).OnRetryReason(func(attempt int, err error, reason Reason) { reasons = append(reasons, reason) }).Exec()
+
+	if len(reasons) != 1 || reasons[0] != ReasonRetryRequested {
+		t.Errorf("expected %v, got %v", ReasonRetryRequested, reasons)
+	}
+}
+
+func TestTimeoutFor(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	errReset := errors.New("connection reset")
+	errOther := errors.New("other")
+	failures := []error{errRefused, errOther, errReset, errOther}
+	calls := 0
+	var timeouts []time.Duration
+	stats := Retry(func() error {
+		calls++
+		return failures[calls-1]
+	}).SetRetries(4).SetTimeout(time.Second).
+		TimeoutFor(func(err error) bool { return errors.Is(err, errRefused) }, 100*time.Millisecond).
+		TimeoutFor(func(err error) bool { return errors.Is(err, errRefused) || errors.Is(err, errReset) }, 50*time.Millisecond).
+		WithTimeoutFunc(func(d time.Duration) <-chan time.Time {
+			timeouts = append(timeouts, d)
+			return nil
+		}).Exec()
+
+	if stats.Attempts != 4 {
+		t.Fatalf("expected 4 attempts, got %d", stats.Attempts)
+	}
+	expected := []time.Duration{time.Second, 100 * time.Millisecond, time.Second, 50 * time.Millisecond}
+	if len(timeouts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, timeouts)
+	}
+	for i := range expected {
+		if timeouts[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, timeouts)
+			break
+		}
+	}
+}
+
+func TestTimeoutForAfterTimeout(t *testing.T) {
+	stats := Retry(func() error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}).SetRetries(2).SetTimeout(5*time.Millisecond).
+		TimeoutFor(func(err error) bool { return errors.Is(err, ErrTimeout) }, time.Second).Exec()
+
+	if stats.Err != nil || stats.Attempts != 2 || stats.Timeout != 1 {
+		t.Errorf("expected a success after a timeout, got %v after %d attempts", stats.Err, stats.Attempts)
+	}
+}
+
+func TestNestedRetriesCancelledTogether(t *testing.T) {
+	started := make(chan struct{})
+	inner := make(chan Stats, 1)
+	outer := RetryContext(context.Background(), func(ctx context.Context) error {
+		stats := RetryContext(ctx, func(ctx context.Context) error {
+			close(started)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return errors.New("not cancelled")
+			}
+		}).SetRetries(5).Exec()
+		inner <- stats
+		return stats.Err
+	}).SetRetries(3)
+	go func() {
+		<-started
+		outer.Cancel()
+	}()
+
+	stats := outer.Exec()
+	if !errors.Is(stats.Err, ErrCancelled) || stats.Attempts != 1 {
+		t.Errorf("expected the outer execution to be cancelled, got %v after %d attempts", stats.Err, stats.Attempts)
+	}
+	select {
+	case stats := <-inner:
+		if !errors.Is(stats.Err, ErrCancelled) || stats.Attempts != 1 {
+			t.Errorf("expected the inner execution to be cancelled mid attempt, got %v after %d attempts", stats.Err, stats.Attempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the inner execution to return")
+	}
+}
+
+func TestBudgetFor(t *testing.T) {
+	errThrottled := errors.New("throttled")
+	errFailed := errors.New("failed")
+	isThrottled := func(err error) bool { return errors.Is(err, errThrottled) }
+
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		if calls%2 == 0 {
+			return errThrottled
+		}
+		return errFailed
+	}).SetRetries(10).BudgetFor(isThrottled, 2).Exec()
+
+	if !errors.Is(stats.Err, ErrExhausted) || stats.Outcome != OutcomeExhausted {
+		t.Errorf("expected %v, got %v (%v)", ErrExhausted, stats.Err, stats.Outcome)
+	}
+	if calls != 6 {
+		t.Errorf("expected to stop at the third throttled error, got %d attempts", calls)
+	}
+
+	calls = 0
+	stats = Retry(func() error {
+		calls++
+		return errFailed
+	}).SetRetries(4).BudgetFor(isThrottled, 1).Exec()
+	if !errors.Is(stats.Err, errFailed) || calls != 4 {
+		t.Errorf("expected the unmatched errors to use the 4 attempts, got %v after %d", stats.Err, calls)
+	}
+}
+
+func TestRetryOnMessage(t *testing.T) {
+	messages := []string{"503 service unavailable", "connection reset by peer", "404 not found"}
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return errors.New(messages[calls-1])
+	}).SetRetries(5).RetryOnMessage(`^5\d\d |connection reset`).Exec()
+
+	if stats.Outcome != OutcomeAborted || stats.Err.Error() != "404 not found" || calls != 3 {
+		t.Errorf("expected to stop at the unmatched message, got %v (%v) after %d attempts", stats.Err, stats.Outcome, calls)
+	}
+}
+
+func TestRetryOnMessageInvalidPattern(t *testing.T) {
+	calls := 0
+	fn := func() error {
+		calls++
+		return nil
+	}
+	if stats := Retry(fn).RetryOnMessage("(").Exec(); !errors.Is(stats.Err, ErrInvalidConfig) || calls != 0 {
+		t.Errorf("expected %v without attempts, got %v after %d", ErrInvalidConfig, stats.Err, calls)
+	}
+	if stats := Retry(fn).RetryOnMessage("(").RetryOnMessage("timeout").Exec(); stats.Err != nil {
+		t.Errorf("expected a valid pattern to replace the invalid one, got %v", stats.Err)
+	}
+	if stats := Retry(fn).RetryOnMessage("(").RetryIf(func(error) bool { return true }).Exec(); stats.Err != nil {
+		t.Errorf("expected RetryIf to replace the invalid pattern, got %v", stats.Err)
+	}
+	if stats := Retry(nil).RetryOnMessage("timeout").Exec(); !errors.Is(stats.Err, ErrNilFunc) {
+		t.Errorf("expected a valid pattern to keep %v, got %v", ErrNilFunc, stats.Err)
+	}
+}
+
+func TestSoftTimeout(t *testing.T) {
+	var slow atomic.Int32
+	stats := Retry(func() error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}).SetTimeout(time.Second).SoftTimeout(5*time.Millisecond, func() { slow.Add(1) }).Exec()
+
+	if stats.Err != nil || stats.Attempts != 1 || stats.Timeout != 0 {
+		t.Errorf("expected the slow attempt to succeed, got %v after %d attempts", stats.Err, stats.Attempts)
+	}
+	if slow.Load() != 1 {
+		t.Errorf("expected the soft timeout callback once, got %d", slow.Load())
+	}
+
+	for _, soft := range []time.Duration{time.Second, 2 * time.Second} {
+		stats := Retry(func() error { return nil }).SetTimeout(time.Second).SoftTimeout(soft, func() {}).Exec()
+		if !errors.Is(stats.Err, ErrInvalidConfig) || stats.Attempts != 0 {
+			t.Errorf("expected %v for a soft timeout of %v, got %v", ErrInvalidConfig, soft, stats.Err)
+		}
+	}
+}
+
+func TestRetryWhile(t *testing.T) {
+	var attempts []int
+	var elapsed []time.Duration
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		time.Sleep(5 * time.Millisecond)
+		return fmt.Errorf("attempt %d failed", calls)
+	}).SetRetries(10).RetryWhile(func(attempt int, d time.Duration, err error) bool {
+		attempts = append(attempts, attempt)
+		elapsed = append(elapsed, d)
+		return attempt < 2
+	}).Exec()
+
+	if stats.Outcome != OutcomeAborted || stats.Err == nil || stats.Err.Error() != "attempt 3 failed" {
+		t.Errorf("expected to stop with the error of the third attempt, got %v (%v)", stats.Err, stats.Outcome)
+	}
+	if calls != 3 || len(attempts) != 3 || attempts[0] != 0 || attempts[2] != 2 {
+		t.Errorf("expected the predicate after each of the 3 attempts, got %v after %d", attempts, calls)
+	}
+	for i, d := range elapsed {
+		if min := time.Duration(i+1) * 5 * time.Millisecond; d < min || (i > 0 && d <= elapsed[i-1]) {
+			t.Errorf("expected the elapsed time since the start of Exec, got %v", elapsed)
+			break
+		}
+	}
+}
+
+func TestBetweenAttempts(t *testing.T) {
+	var events []string
+	Retry(func() error {
+		events = append(events, "attempt")
+		return errors.New("failed")
+	}).SetRetries(2).SetSleep(time.Millisecond).
+		OnRetry(func(int, error) { events = append(events, "retry") }).
+		BetweenAttempts(func(attempt int, err error) error {
+			events = append(events, fmt.Sprintf("between %d: %v", attempt, err))
+			return nil
+		}).
+		WithSleepFunc(func(time.Duration) { events = append(events, "sleep") }).Exec()
+
+	expected := []string{"attempt", "retry", "between 0: failed", "sleep", "attempt"}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, events)
+			break
+		}
+	}
+}
+
+func TestBetweenAttemptsError(t *testing.T) {
+	errReconnect := errors.New("reconnect failed")
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return errors.New("failed")
+	}).SetRetries(3).BetweenAttempts(func(int, error) error { return errReconnect }).Exec()
+
+	if !errors.Is(stats.Err, errReconnect) || stats.Outcome != OutcomeAborted || calls != 1 {
+		t.Errorf("expected %v after 1 attempt, got %v (%v) after %d", errReconnect, stats.Err, stats.Outcome, calls)
+	}
+}
+
+// recordingRunner runs every attempt in its own goroutine, counts them and
+// reports the errors of the contexts done before the attempt returned.
+type recordingRunner struct {
+	runs      atomic.Int32
+	cancelled chan error
+}
+
+func (r *recordingRunner) Run(ctx context.Context, fn func() error) error {
+	r.runs.Add(1)
+	result := make(chan error, 1)
+	go func() { result <- fn() }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		r.cancelled <- ctx.Err()
+		return ctx.Err()
+	}
+}
+
+func TestSetRunner(t *testing.T) {
+	runner := &recordingRunner{cancelled: make(chan error, 3)}
+	var calls atomic.Int32
+	stats := Retry(func() error {
+		if calls.Add(1) == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return errors.New("failed")
+	}).SetRetries(3).SetTimeout(5 * time.Millisecond).SetRunner(runner).Exec()
+
+	if stats.Attempts != 3 || stats.Timeout != 1 {
+		t.Errorf("expected 3 attempts with 1 timeout, got %d with %d", stats.Attempts, stats.Timeout)
+	}
+	if runner.runs.Load() != 3 {
+		t.Errorf("expected the runner to run every attempt once, got %d runs", runner.runs.Load())
+	}
+	select {
+	case err := <-runner.cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the context of the timed out attempt to be cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the context of the timed out attempt to be done")
+	}
+}
+
+// panicBackoff panics the given number of times, to fail the execution
+// after its first attempt.
+type panicBackoff struct {
+	panics *atomic.Int32
+}
+
+func (b panicBackoff) Delay(int) time.Duration {
+	if b.panics.Add(-1) >= 0 {
+		panic("backoff")
+	}
+	return 0
+}
+
+func (b panicBackoff) Name() string {
+	return "panic"
+}
+
+func TestPanicIsRecovered(t *testing.T) {
+	var panics atomic.Int32
+	panics.Store(1)
+	stats := Retry(func() error { return errors.New("failed") }).
+		SetRetries(3).SetBackoff(panicBackoff{panics: &panics}).Exec()
+
+	if !errors.Is(stats.Err, ErrPanic) {
+		t.Fatalf("expected %v, got %v", ErrPanic, stats.Err)
+	}
+	if !strings.Contains(stats.Err.Error(), "backoff") {
+		t.Errorf("expected the value of the panic in the error, got %v", stats.Err)
+	}
+	if stats.Outcome != OutcomeAborted {
+		t.Errorf("expected outcome %v, got %v", OutcomeAborted, stats.Outcome)
+	}
+	if stats.Attempts != 1 || stats.Successes != 0 {
+		t.Errorf("expected the failed attempt before the panic, got %d attempts with %d successes", stats.Attempts, stats.Successes)
+	}
+}
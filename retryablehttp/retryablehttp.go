@@ -0,0 +1,269 @@
+// The retryablehttp package retries HTTP requests with the retryable
+// package. The network errors and the responses with status 429 or 5xx are
+// retried, any other response is returned to the caller. By default only the
+// idempotent methods are retried, see RetryMethods.
+//
+// Example:
+//
+//	client := retryablehttp.NewClient(http.DefaultClient).
+//		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3).SetSleep(time.Second) }).
+//		AbortOnHeader("X-No-Retry", "true")
+//
+//	resp, stats := client.Do(req)
+//	if stats.Err != nil {
+//		return stats.Err
+//	}
+//	defer resp.Body.Close()
+package retryablehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lazaroMB/retryable"
+)
+
+// ErrBodyNotRewindable is the error of a retry of a request with a body that
+// can not be read again, because the request has no GetBody.
+var ErrBodyNotRewindable = errors.New("Request body can not be rewound to retry")
+
+// StatusError is the error of an attempt whose response has a retryable
+// status code, or the server asked not to retry it with AbortOnHeader.
+type StatusError struct {
+	StatusCode int
+	Aborted    bool
+}
+
+func (e *StatusError) Error() string {
+	if e.Aborted {
+		return fmt.Sprintf("HTTP status %d, the server asked not to retry", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP status %d", e.StatusCode)
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+// IDEMPOTENCY_KEY_HEADER is the header that makes a request of any method
+// retryable.
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
+// DefaultRetryMethods are the methods retried by default, the idempotent ones.
+var DefaultRetryMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPut,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// Client executes HTTP requests with an http.Client retrying them.
+type Client struct {
+	client       *http.Client
+	configure    func(rt retryable.RetrayableI)
+	abortHeaders []header
+	methods      map[string]bool
+}
+
+// The function NewClient is creating and returning a Client that executes the
+// requests with client, http.DefaultClient when it is nil.
+func NewClient(client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := &Client{client: client}
+	c.RetryMethods(DefaultRetryMethods...)
+	return c
+}
+
+// The RetryMethods method sets the methods whose requests are retried, it
+// replaces DefaultRetryMethods. The requests of the other methods, like POST,
+// are executed only once, even when they time out, unless they have an
+// Idempotency-Key header that makes the server discard the duplicates. It
+// returns the Client, allowing method chaining.
+func (c *Client) RetryMethods(methods ...string) *Client {
+	c.methods = map[string]bool{}
+	for _, method := range methods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+	return c
+}
+
+// retryable returns true if req can be executed more than once.
+func (c *Client) retryable(req *http.Request) bool {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return c.methods[method] || req.Header.Get(IDEMPOTENCY_KEY_HEADER) != ""
+}
+
+// The Configure method sets a function that receives the RetrayableI of every
+// request before executing it, to set its retries, sleep and so on. The
+// timeout of the http.Client bounds every attempt. It returns the Client,
+// allowing method chaining.
+func (c *Client) Configure(fn func(rt retryable.RetrayableI)) *Client {
+	c.configure = fn
+	return c
+}
+
+// The AbortOnHeader method stops retrying as soon as a response with a
+// retryable status, 429 or 5xx, has the header name with the given value,
+// compared without case, and Do returns a StatusError with Aborted set. The
+// responses of the other statuses are never retried, so they are returned
+// with a nil error whether they have the header or not. It can be called
+// several times to recognize several headers. It returns the Client, allowing
+// method chaining.
+func (c *Client) AbortOnHeader(name, value string) *Client {
+	c.abortHeaders = append(c.abortHeaders, header{name: name, value: value})
+	return c
+}
+
+// aborted returns true if resp has any of the AbortOnHeader headers.
+func (c *Client) aborted(resp *http.Response) bool {
+	for _, h := range c.abortHeaders {
+		for _, value := range resp.Header.Values(h.name) {
+			if strings.EqualFold(strings.TrimSpace(value), h.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryableStatus returns true if the status code of a response must be retried.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// The Do method executes req retrying it and returns the response of the
+// successful attempt together with the Stats of the execution. The response
+// is nil when stats.Err is not nil or when no attempt returned it, like after
+// a Fallback, and the body of every discarded response is closed, including
+// the ones of the attempts abandoned after a timeout. Every attempt runs with
+// its own context, derived from the one of req, which is cancelled when the
+// attempt is abandoned, and the context of the returned response ends when
+// its body is closed.
+func (c *Client) Do(req *http.Request) (*http.Response, retryable.Stats) {
+	var mu sync.Mutex
+	responses := map[int]*http.Response{}
+	attempts := 0
+	retry := c.retryable(req)
+	rt := retryable.RetryBoolContext(req.Context(), func(ctx context.Context) (bool, error) {
+		mu.Lock()
+		n := attempts
+		attempts++
+		mu.Unlock()
+		reqCtx, cancel := context.WithCancel(req.Context())
+		var kept atomic.Bool
+		go func() {
+			select {
+			case <-ctx.Done():
+				if !kept.Load() {
+					cancel()
+				}
+			case <-reqCtx.Done():
+			}
+		}()
+
+		attempt := req.Clone(reqCtx)
+		if n > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				cancel()
+				return false, ErrBodyNotRewindable
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return false, err
+			}
+			attempt.Body = body
+		}
+
+		res, err := c.client.Do(attempt)
+		if err != nil {
+			cancel()
+			return retry, err
+		}
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+		aborted := c.aborted(res)
+		if retryableStatus(res.StatusCode) {
+			res.Body.Close()
+			return retry && !aborted, &StatusError{StatusCode: res.StatusCode, Aborted: aborted}
+		}
+		kept.Store(true)
+		if ctx.Err() != nil {
+			// the attempt was abandoned while waiting for the response
+			res.Body.Close()
+			return false, ctx.Err()
+		}
+		mu.Lock()
+		responses[n] = res
+		mu.Unlock()
+		return false, nil
+	})
+	if c.configure != nil {
+		c.configure(rt)
+	}
+	if !retry {
+		// a timed out request may still reach the server
+		rt.Idempotent(false)
+	}
+	stats := rt.Exec()
+
+	mu.Lock()
+	defer mu.Unlock()
+	resp := successResponse(responses, stats)
+	for _, res := range responses {
+		if res != resp {
+			res.Body.Close()
+		}
+	}
+	return resp, stats
+}
+
+// successResponse returns the response of the successful attempt among the
+// responses of the attempts, or nil if none of them is. The successful
+// attempt is the last one, unless the attempts ran in parallel, then it is
+// the first one that returned a response, which is only known when no
+// attempt timed out.
+func successResponse(responses map[int]*http.Response, stats retryable.Stats) *http.Response {
+	if stats.Err != nil || stats.UsedFallback {
+		return nil
+	}
+	if resp, ok := responses[stats.Retries]; ok {
+		return resp
+	}
+	if stats.Timeout > 0 {
+		return nil
+	}
+	first := -1
+	for attempt := range responses {
+		if first < 0 || attempt < first {
+			first = attempt
+		}
+	}
+	return responses[first]
+}
+
+// cancelBody is the body of a response that cancels the context of its
+// request once closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,256 @@
+package retryablehttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazaroMB/retryable"
+)
+
+// server returns a test server that answers the n-th request, starting at 0,
+// with handlers[n], or the last handler for the requests after them.
+func server(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1)) - 1
+		if n >= len(handlers) {
+			n = len(handlers) - 1
+		}
+		handlers[n](w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func status(code int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		io.WriteString(w, body)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error reading the body: %v", err)
+	}
+	return string(body)
+}
+
+func TestDoRetriesRetryableStatus(t *testing.T) {
+	srv, requests := server(t, status(http.StatusServiceUnavailable, "down"), status(http.StatusOK, "ok"))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+
+	resp, stats := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3) }).
+		Do(req)
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if body := readBody(t, resp); body != "ok" {
+		t.Errorf("expected %q, got %q", "ok", body)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestDoDiscardsTimedOutAttempt(t *testing.T) {
+	abandoned := make(chan struct{})
+	srv, _ := server(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+				close(abandoned)
+			case <-time.After(time.Second):
+				io.WriteString(w, "stale")
+			}
+		},
+		status(http.StatusOK, "fresh"),
+	)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+
+	resp, stats := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(2).SetTimeout(50 * time.Millisecond) }).
+		Do(req)
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if body := readBody(t, resp); body != "fresh" {
+		t.Errorf("expected %q, got %q", "fresh", body)
+	}
+	select {
+	case <-abandoned:
+	case <-time.After(time.Second):
+		t.Error("expected the request of the timed out attempt to be cancelled")
+	}
+}
+
+func TestDoExhaustedReturnsNoResponse(t *testing.T) {
+	srv, requests := server(t, status(http.StatusBadGateway, "down"))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+
+	resp, stats := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3) }).
+		Do(req)
+
+	var statusErr *StatusError
+	if !errors.As(stats.Err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected a StatusError 502, got %v", stats.Err)
+	}
+	if resp != nil {
+		t.Error("expected no response")
+	}
+	if requests.Load() != 3 {
+		t.Errorf("expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func TestDoFallbackReturnsNoResponse(t *testing.T) {
+	srv, _ := server(t, status(http.StatusServiceUnavailable, "down"))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+
+	resp, stats := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) {
+			rt.SetRetries(2).Fallback(func(error) error { return nil })
+		}).
+		Do(req)
+
+	if stats.Err != nil || !stats.UsedFallback {
+		t.Fatalf("expected a fallback success, got %v", stats.Err)
+	}
+	if resp != nil {
+		t.Error("expected no response")
+	}
+}
+
+func TestDoNotRetryPost(t *testing.T) {
+	srv, requests := server(t, status(http.StatusServiceUnavailable, "down"))
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+
+	_, stats := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3) }).
+		Do(req)
+
+	if stats.Err == nil {
+		t.Fatal("expected an error")
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+}
+
+func TestDoNotRetryTimedOutPost(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}
+	srv, requests := server(t, slow)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+
+	_, stats := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3).SetTimeout(10 * time.Millisecond).Idempotent(true) }).
+		Do(req)
+
+	if !errors.Is(stats.Err, retryable.ErrTimeout) || stats.Attempts != 1 {
+		t.Errorf("expected %v after 1 attempt, got %v after %d", retryable.ErrTimeout, stats.Err, stats.Attempts)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+}
+
+func TestDoRetryMethods(t *testing.T) {
+	tests := []struct {
+		method   string
+		requests int32
+	}{
+		{http.MethodPost, 3},
+		{http.MethodGet, 1},
+	}
+	for _, test := range tests {
+		srv, requests := server(t, status(http.StatusServiceUnavailable, "down"))
+		req, _ := http.NewRequest(test.method, srv.URL, strings.NewReader("payload"))
+
+		NewClient(nil).
+			Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3) }).
+			RetryMethods("post").
+			Do(req)
+
+		if requests.Load() != test.requests {
+			t.Errorf("%s: expected %d requests, got %d", test.method, test.requests, requests.Load())
+		}
+	}
+}
+
+func TestDoRewindsBodyWithIdempotencyKey(t *testing.T) {
+	var bodies []string
+	record := func(code int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			w.WriteHeader(code)
+		}
+	}
+	srv, _ := server(t, record(http.StatusInternalServerError), record(http.StatusCreated))
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	req.Header.Set(IDEMPOTENCY_KEY_HEADER, "key")
+
+	resp, stats := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(2) }).
+		Do(req)
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Errorf("expected the body sent twice, got %q", bodies)
+	}
+}
+
+func TestDoAbortOnHeader(t *testing.T) {
+	header := func(code int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-No-Retry", "TRUE")
+			w.WriteHeader(code)
+		}
+	}
+
+	srv, requests := server(t, header(http.StatusServiceUnavailable))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	client := NewClient(nil).
+		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3) }).
+		AbortOnHeader("X-No-Retry", "true")
+
+	resp, stats := client.Do(req)
+	var statusErr *StatusError
+	if !errors.As(stats.Err, &statusErr) || !statusErr.Aborted {
+		t.Fatalf("expected an aborted StatusError, got %v", stats.Err)
+	}
+	if resp != nil || requests.Load() != 1 {
+		t.Errorf("expected 1 request and no response, got %d requests", requests.Load())
+	}
+
+	srv, _ = server(t, header(http.StatusNotFound))
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, stats = client.Do(req)
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+}
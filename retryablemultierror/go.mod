@@ -0,0 +1,14 @@
+module github.com/lazaroMB/retryable/retryablemultierror
+
+go 1.20
+
+require (
+	github.com/hashicorp/go-multierror v1.1.1
+	github.com/lazaroMB/retryable v0.0.0-20261014041809-61a90c3794f8
+)
+
+require github.com/hashicorp/errwrap v1.0.0 // indirect
+
+// Only used to develop in this repository, the modules that depend on this
+// one ignore it and get the required version.
+replace github.com/lazaroMB/retryable => ../
@@ -0,0 +1,36 @@
+// The retryablemultierror package collects the errors of the attempts of a
+// retryable function into a *multierror.Error of
+// github.com/hashicorp/go-multierror, for the codebases standardized on it.
+// It is a separate module, so the retryable package does not depend on
+// go-multierror.
+//
+// Example:
+//
+//	stats, errs := retryablemultierror.Exec(retryable.Retry(PollApi).SetRetries(3))
+//
+//	if stats.Err != nil {
+//		log.Println(errs) // every attempt error, not only the last one
+//	}
+package retryablemultierror
+
+import (
+	"github.com/hashicorp/go-multierror"
+	"github.com/lazaroMB/retryable"
+)
+
+// The function Errors converts the errors collected with CollectErrors in
+// stats into a *multierror.Error, it returns nil when there is none.
+func Errors(stats retryable.Stats) *multierror.Error {
+	if len(stats.Errors) == 0 {
+		return nil
+	}
+	return multierror.Append(nil, stats.Errors...)
+}
+
+// The function Exec enables CollectErrors on rt, executes it and returns its
+// Stats together with the errors of all its failed attempts, nil when no
+// attempt failed.
+func Exec(rt retryable.RetrayableI) (retryable.Stats, *multierror.Error) {
+	stats := rt.CollectErrors(true).Exec()
+	return stats, Errors(stats)
+}
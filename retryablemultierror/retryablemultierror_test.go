@@ -0,0 +1,47 @@
+package retryablemultierror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lazaroMB/retryable"
+)
+
+func TestExecCollectsEveryError(t *testing.T) {
+	calls := 0
+	stats, errs := Exec(retryable.Retry(func() error {
+		calls++
+		return fmt.Errorf("attempt %d failed", calls)
+	}).SetRetries(3))
+
+	if stats.Err == nil {
+		t.Fatal("expected an error")
+	}
+	if errs == nil || len(errs.Errors) != 3 {
+		t.Fatalf("expected the 3 errors of the attempts, got %v", errs)
+	}
+	for i, err := range errs.Errors {
+		if expected := fmt.Sprintf("attempt %d failed", i+1); err.Error() != expected {
+			t.Errorf("expected %q, got %q", expected, err)
+		}
+	}
+}
+
+func TestExecWithoutFailures(t *testing.T) {
+	stats, errs := Exec(retryable.Retry(func() error { return nil }))
+	if stats.Err != nil || errs != nil {
+		t.Errorf("expected no error, got %v and %v", stats.Err, errs)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+	errs := Errors(retryable.Stats{Errors: []error{errFailed, retryable.ErrTimeout}})
+	if !errors.Is(errs, errFailed) || !errors.Is(errs, retryable.ErrTimeout) {
+		t.Errorf("expected both errors, got %v", errs)
+	}
+	if Errors(retryable.Stats{}) != nil {
+		t.Error("expected nil without errors")
+	}
+}
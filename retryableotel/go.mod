@@ -0,0 +1,15 @@
+module github.com/lazaroMB/retryable/retryableotel
+
+go 1.25.0
+
+require (
+	github.com/lazaroMB/retryable v0.0.0-20261014041809-61a90c3794f8
+	go.opentelemetry.io/otel v1.46.0
+	go.opentelemetry.io/otel/metric v1.46.0
+)
+
+require github.com/cespare/xxhash/v2 v2.3.0 // indirect
+
+// Only used to develop in this repository, the modules that depend on this
+// one ignore it and get the required version.
+replace github.com/lazaroMB/retryable => ../
@@ -0,0 +1,83 @@
+// The retryableotel package records OpenTelemetry metrics of the executions
+// of a retryable function. It is a separate module, so the retryable package
+// does not depend on OpenTelemetry. It requires Go 1.25, the minimum of the
+// OpenTelemetry release it uses, the retryable package only requires Go 1.20.
+//
+// The recorded metrics are:
+//   - retryable.attempts: counter of the attempts executed.
+//   - retryable.retries: histogram of the retries of each execution.
+//   - retryable.executions: counter of the executions, with the outcome
+//     attribute set to the Outcome of the execution (Success, Exhausted, ...).
+//
+// Example:
+//
+//	rt, err := retryableotel.Wrap(
+//	  retryable.Retry(PollApi).SetRetries(3),
+//	  otel.Meter("my-service"),
+//	)
+//
+//	stats := rt.Exec()
+//	<-rt.SetRetries(5).ExecAsync() // recorded too
+package retryableotel
+
+import (
+	"context"
+
+	"github.com/lazaroMB/retryable"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metric names and attributes
+const (
+	ATTEMPTS_METRIC   = "retryable.attempts"
+	RETRIES_METRIC    = "retryable.retries"
+	EXECUTIONS_METRIC = "retryable.executions"
+	OUTCOME_ATTRIBUTE = "outcome"
+)
+
+// recorder records the metrics of the executions observed.
+type recorder struct {
+	attempts   metric.Int64Counter
+	retries    metric.Int64Histogram
+	executions metric.Int64Counter
+	attributes []attribute.KeyValue
+}
+
+// record records the metrics of an execution.
+func (r *recorder) record(stats retryable.Stats) {
+	ctx := context.Background()
+	attrs := metric.WithAttributes(r.attributes...)
+	r.attempts.Add(ctx, int64(stats.Attempts), attrs)
+	r.retries.Record(ctx, int64(stats.Retries), attrs)
+	outcome := append([]attribute.KeyValue{attribute.String(OUTCOME_ATTRIBUTE, stats.Outcome.String())}, r.attributes...)
+	r.executions.Add(ctx, 1, metric.WithAttributes(outcome...))
+}
+
+// The function Wrap records the metrics of every execution of rt using the
+// meter, attrs are added to all the metrics, and returns rt. The metrics are
+// recorded with Observe, so rt keeps all its settings and hooks, and the
+// executions started with Exec, ExecAsync, ExecFuture or AsFunc are all
+// recorded. The Fields of WithFields are not added to the attributes, their
+// values, like correlation IDs, would blow up the cardinality of the metrics.
+func Wrap(rt retryable.RetrayableI, meter metric.Meter, attrs ...attribute.KeyValue) (retryable.RetrayableI, error) {
+	attempts, err := meter.Int64Counter(ATTEMPTS_METRIC, metric.WithDescription("Attempts executed"))
+	if err != nil {
+		return nil, err
+	}
+	retries, err := meter.Int64Histogram(RETRIES_METRIC, metric.WithDescription("Retries of each execution"))
+	if err != nil {
+		return nil, err
+	}
+	executions, err := meter.Int64Counter(EXECUTIONS_METRIC, metric.WithDescription("Executions by outcome"))
+	if err != nil {
+		return nil, err
+	}
+	r := &recorder{
+		attempts:   attempts,
+		retries:    retries,
+		executions: executions,
+		attributes: attrs,
+	}
+	return rt.Observe(r.record), nil
+}
@@ -0,0 +1,101 @@
+package retryableotel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lazaroMB/retryable"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// meter is a Meter that sums the values added to its counters and
+// histograms by name.
+type meter struct {
+	noop.Meter
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+func newMeter() *meter {
+	return &meter{values: map[string]int64{}}
+}
+
+func (m *meter) add(name string, value int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] += value
+}
+
+func (m *meter) value(name string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[name]
+}
+
+func (m *meter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return counter{meter: m, name: name}, nil
+}
+
+func (m *meter) Int64Histogram(name string, _ ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return histogram{meter: m, name: name}, nil
+}
+
+type counter struct {
+	noop.Int64Counter
+	meter *meter
+	name  string
+}
+
+func (c counter) Add(_ context.Context, value int64, _ ...metric.AddOption) {
+	c.meter.add(c.name, value)
+}
+
+type histogram struct {
+	noop.Int64Histogram
+	meter *meter
+	name  string
+}
+
+func (h histogram) Record(_ context.Context, value int64, _ ...metric.RecordOption) {
+	h.meter.add(h.name, value)
+}
+
+func TestWrapRecordsEveryExecution(t *testing.T) {
+	m := newMeter()
+	rt, err := Wrap(retryable.Retry(func() error { return errors.New("failed") }), m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rt.SetRetries(2).Exec()
+	<-rt.ExecAsync()
+	rt.ExecFuture().Await()
+	rt.AsFunc()()
+
+	if got := m.value(EXECUTIONS_METRIC); got != 4 {
+		t.Errorf("expected 4 executions, got %d", got)
+	}
+	if got := m.value(ATTEMPTS_METRIC); got != 8 {
+		t.Errorf("expected 8 attempts, got %d", got)
+	}
+	if got := m.value(RETRIES_METRIC); got != 4 {
+		t.Errorf("expected 4 retries, got %d", got)
+	}
+}
+
+func TestWrapKeepsTheHooks(t *testing.T) {
+	var finalized, gaveUp bool
+	rt, err := Wrap(retryable.Retry(func() error { return errors.New("failed") }).
+		OnGiveUp(func(retryable.Stats) { gaveUp = true }), newMeter())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rt.Finalize(func(*retryable.Stats) { finalized = true }).Exec()
+	if !gaveUp || !finalized {
+		t.Errorf("expected OnGiveUp and Finalize to be called, got %v and %v", gaveUp, finalized)
+	}
+}
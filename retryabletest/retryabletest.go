@@ -0,0 +1,35 @@
+// The retryabletest package provides utilities to test code that uses the
+// retryable package.
+//
+// Example:
+//
+//	fn, calls := retryabletest.CountingFunc(2)
+//
+//	stats := retryable.Retry(fn).SetRetries(3).Exec()
+//
+//	if stats.Err != nil || *calls != 3 {
+//		t.Fatalf("expected 3 calls, got %d: %v", *calls, stats.Err)
+//	}
+package retryabletest
+
+import "errors"
+
+// ErrCountingFunc is the error returned by the functions of CountingFunc
+// while they are failing.
+var ErrCountingFunc = errors.New("Counting function failed")
+
+// The function CountingFunc returns a function that fails with
+// ErrCountingFunc the first failTimes calls and succeeds after them, together
+// with a counter of the calls made to it.
+// The counter is not synchronized, read it once Exec has returned and do not
+// use it with timed out attempts that could still be running.
+func CountingFunc(failTimes int) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= failTimes {
+			return ErrCountingFunc
+		}
+		return nil
+	}, &calls
+}
@@ -0,0 +1,98 @@
+package retryable
+
+import (
+	"context"
+	"sync"
+)
+
+// The State field is the state returned by the last attempt that did not time
+// out, the one received by the next attempt, or the initial state when there
+// is none. On success it is the state returned by the successful attempt.
+type StateStats[S any] struct {
+	Stats
+	State S
+}
+
+// RetrayableState retries a function whose input evolves across the
+// attempts, like increasing a page size or switching to a fallback region:
+// every attempt receives the state returned by the previous one. It embeds
+// RetrayableI, so every setting is available, use Configure to keep chaining:
+//
+//	stats := retryable.RetryWithState("eu-west-1", FetchFromRegion).
+//		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3) }).
+//		Exec()
+//	fmt.Println(stats.State)
+//
+// A timed out attempt leaves the state unchanged, the next attempt receives
+// the same state as it, and what it returns after the timeout is discarded.
+// With ParallelQuorum every attempt receives the initial state.
+type RetrayableState[S any] struct {
+	RetrayableI
+	initial S
+	mu      sync.Mutex
+	run     int
+	state   S
+	settled int
+	pending map[int]S
+}
+
+// The function RetryWithState is creating and returning an instance of the
+// type RetrayableState. The function takes the initial state and an argument
+// fn, which is a function that receives the current state and returns the
+// state for the next attempt and an error. Every Exec starts again from the
+// initial state.
+func RetryWithState[S any](initial S, fn func(state S) (S, error)) *RetrayableState[S] {
+	rs := &RetrayableState[S]{initial: initial, state: initial, pending: map[int]S{}}
+	rt := newRetrayable(context.Background(), func(_ context.Context, attempt int) error {
+		rs.mu.Lock()
+		run, state := rs.run, rs.state
+		rs.mu.Unlock()
+
+		next, err := fn(state)
+		rs.mu.Lock()
+		// attempts that outlive their Exec must not leak into the next one
+		if run == rs.run && attempt >= rs.settled {
+			rs.pending[attempt] = next
+		}
+		rs.mu.Unlock()
+		return err
+	}).requireFunc(fn == nil)
+	rt.settle = func(attempt int, timedOut bool) {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		if next, ok := rs.pending[attempt]; ok && !timedOut {
+			rs.state = next
+		}
+		delete(rs.pending, attempt)
+		rs.settled = attempt + 1
+	}
+	rs.RetrayableI = rt
+	return rs
+}
+
+// The Configure method calls fn with the RetrayableI of the instance to change
+// its settings and returns the typed instance, so the chain can end with its
+// typed Exec.
+func (r *RetrayableState[S]) Configure(fn func(rt RetrayableI)) *RetrayableState[S] {
+	fn(r.RetrayableI)
+	return r
+}
+
+// The Exec method executes the function with the specified settings, threading
+// the state through the attempts, and returns a StateStats with the Stats of
+// the execution and the final state.
+func (r *RetrayableState[S]) Exec() StateStats[S] {
+	r.mu.Lock()
+	r.run++
+	r.state = r.initial
+	r.settled = 0
+	r.pending = map[int]S{}
+	r.mu.Unlock()
+
+	stats := StateStats[S]{Stats: r.RetrayableI.Exec()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats.State = r.state
+	return stats
+}
@@ -0,0 +1,57 @@
+package retryable
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryWithStateThreadsTheState(t *testing.T) {
+	regions := map[string]string{"eu-west-1": "eu-central-1", "eu-central-1": "us-east-1"}
+	var received []string
+	rs := RetryWithState("eu-west-1", func(region string) (string, error) {
+		received = append(received, region)
+		if region != "us-east-1" {
+			return regions[region], errors.New("unavailable")
+		}
+		return region, nil
+	}).Configure(func(rt RetrayableI) { rt.SetRetries(5) })
+
+	stats := rs.Exec()
+	if stats.Err != nil || stats.State != "us-east-1" {
+		t.Fatalf("expected a success in us-east-1, got %v in %q", stats.Err, stats.State)
+	}
+	if len(received) != 3 || received[0] != "eu-west-1" || received[1] != "eu-central-1" {
+		t.Errorf("expected every attempt to receive the previous state, got %v", received)
+	}
+
+	received = nil
+	if stats := rs.Exec(); stats.Attempts != 3 || received[0] != "eu-west-1" {
+		t.Errorf("expected Exec to start again from the initial state, got %v", received)
+	}
+}
+
+func TestRetryWithStateTimeoutKeepsTheState(t *testing.T) {
+	var calls atomic.Int32
+	var seen [3]atomic.Int32
+	stats := RetryWithState(0, func(size int) (int, error) {
+		n := calls.Add(1)
+		seen[n-1].Store(int32(size))
+		if n == 2 {
+			time.Sleep(20 * time.Millisecond)
+			return 100, errors.New("late")
+		}
+		return size + 10, errors.New("too small")
+	}).Configure(func(rt RetrayableI) { rt.SetRetries(3).SetTimeout(5 * time.Millisecond) }).Exec()
+
+	if stats.Err == nil {
+		t.Fatal("expected an error")
+	}
+	if seen[1].Load() != 10 || seen[2].Load() != 10 {
+		t.Errorf("expected the attempt after the timeout to receive the same state, got %d and %d", seen[1].Load(), seen[2].Load())
+	}
+	if stats.State != 20 {
+		t.Errorf("expected the state of the last attempt that did not time out, got %d", stats.State)
+	}
+}
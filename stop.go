@@ -0,0 +1,33 @@
+package retryable
+
+import "time"
+
+// StopCondition decides, after a failed attempt, if the execution must stop
+// instead of retrying. It receives the Stats of the execution so far and the
+// time elapsed since the start of Exec.
+type StopCondition func(stats Stats, elapsed time.Duration) bool
+
+// The function AfterAttempts returns a StopCondition met once n attempts
+// were executed.
+func AfterAttempts(n int) StopCondition {
+	return func(stats Stats, _ time.Duration) bool {
+		return stats.Attempts >= n
+	}
+}
+
+// The function AfterElapsed returns a StopCondition met once the execution
+// took d or more. Unlike MaxElapsed it is only checked after a failed attempt,
+// so it never interrupts an attempt or a sleep.
+func AfterElapsed(d time.Duration) StopCondition {
+	return func(_ Stats, elapsed time.Duration) bool {
+		return elapsed >= d
+	}
+}
+
+// The function AfterTimeouts returns a StopCondition met once n attempts
+// timed out.
+func AfterTimeouts(n int) StopCondition {
+	return func(stats Stats, _ time.Duration) bool {
+		return stats.Timeout >= n
+	}
+}
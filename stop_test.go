@@ -0,0 +1,65 @@
+package retryable
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopWhenAnyCondition(t *testing.T) {
+	errFailed := errors.New("failed")
+	stats := Retry(func() error { return errFailed }).
+		RetryForever().StopWhen(AfterElapsed(time.Hour), AfterAttempts(3)).Exec()
+
+	if stats.Attempts != 3 {
+		t.Errorf("expected to stop after 3 attempts, got %d", stats.Attempts)
+	}
+	if !errors.Is(stats.Err, errFailed) {
+		t.Errorf("expected %v, got %v", errFailed, stats.Err)
+	}
+}
+
+func TestStopWhenAfterTimeouts(t *testing.T) {
+	stats := Retry(func() error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}).SetRetries(10).SetTimeout(time.Millisecond).StopWhen(AfterTimeouts(2)).Exec()
+
+	if stats.Attempts != 2 || stats.Timeout != 2 {
+		t.Errorf("expected to stop after 2 timeouts, got %d in %d attempts", stats.Timeout, stats.Attempts)
+	}
+}
+
+func TestStopWhenAfterElapsed(t *testing.T) {
+	stats := Retry(func() error { return errors.New("failed") }).
+		RetryForever().SetSleep(5 * time.Millisecond).StopWhen(AfterElapsed(20 * time.Millisecond)).Exec()
+
+	if stats.Attempts < 2 || stats.Attempts > 10 {
+		t.Errorf("expected to stop after about 20ms, got %d attempts", stats.Attempts)
+	}
+}
+
+func TestStopWhenFlag(t *testing.T) {
+	var flag atomic.Bool
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		if calls == 2 {
+			flag.Store(true)
+		}
+		return errors.New("failed")
+	}).SetRetries(5).StopWhenFlag(&flag).Exec()
+
+	if !errors.Is(stats.Err, ErrCancelled) || !errors.Is(stats.Err, ErrStopFlag) || stats.Outcome != OutcomeCancelled {
+		t.Errorf("expected %v and %v, got %v (%v)", ErrCancelled, ErrStopFlag, stats.Err, stats.Outcome)
+	}
+	if calls != 2 {
+		t.Errorf("expected the running attempt to finish and no other to start, got %d attempts", calls)
+	}
+
+	calls = 0
+	if stats := Retry(func() error { calls++; return nil }).StopWhenFlag(&flag).Exec(); calls != 0 || !errors.Is(stats.Err, ErrStopFlag) {
+		t.Errorf("expected no attempt with the flag already set, got %d and %v", calls, stats.Err)
+	}
+}
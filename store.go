@@ -0,0 +1,76 @@
+package retryable
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RetryStore coordinates the attempts of the instances that share a name,
+// even in different processes when it is backed by a shared storage like
+// Redis, so they do not all retry at the same time.
+//
+// Reserve is called before every attempt with its zero based number and
+// returns how long the instance must wait before executing it, an error
+// aborts the execution with that error. Record is called after every attempt
+// with its error (nil on success), its own error is ignored.
+type RetryStore interface {
+	Reserve(ctx context.Context, name string, attempt int) (time.Duration, error)
+	Record(ctx context.Context, name string, err error) error
+}
+
+type memoryEntry struct {
+	failures    int
+	lastFailure time.Time
+}
+
+// MemoryStore is an in-memory RetryStore to coordinate the instances of a
+// single process. After a failure recorded for a name, every instance
+// sharing it waits for the backoff of the consecutive failures of the name,
+// counted from the last failure, and a success resets them.
+type MemoryStore struct {
+	mu      sync.Mutex
+	backoff Backoff
+	entries map[string]*memoryEntry
+}
+
+// The function NewMemoryStore is creating and returning a MemoryStore that
+// computes the wait after the consecutive failures with backoff.
+func NewMemoryStore(backoff Backoff) *MemoryStore {
+	return &MemoryStore{backoff: backoff, entries: map[string]*memoryEntry{}}
+}
+
+// The Reserve method returns the time left until the backoff after the last
+// failure of name passes.
+func (s *MemoryStore) Reserve(_ context.Context, name string, _ int) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[name]
+	if !ok || entry.failures == 0 {
+		return 0, nil
+	}
+	wait := time.Until(entry.lastFailure.Add(s.backoff.Delay(entry.failures - 1)))
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+// The Record method counts a failure of name, or resets its failures when
+// err is nil.
+func (s *MemoryStore) Record(_ context.Context, name string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.entries, name)
+		return nil
+	}
+	entry, ok := s.entries[name]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[name] = entry
+	}
+	entry.failures++
+	entry.lastFailure = time.Now()
+	return nil
+}
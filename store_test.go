@@ -0,0 +1,78 @@
+package retryable
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(Exponential(time.Minute, time.Hour))
+	if wait, _ := store.Reserve(ctx, "api", 0); wait != 0 {
+		t.Fatalf("expected no wait without failures, got %v", wait)
+	}
+
+	store.Record(ctx, "api", errors.New("failed"))
+	if wait, _ := store.Reserve(ctx, "api", 0); wait <= 59*time.Second || wait > time.Minute {
+		t.Errorf("expected to wait the backoff of 1 failure, got %v", wait)
+	}
+	store.Record(ctx, "api", errors.New("failed"))
+	if wait, _ := store.Reserve(ctx, "api", 0); wait <= 119*time.Second || wait > 2*time.Minute {
+		t.Errorf("expected to wait the backoff of 2 failures, got %v", wait)
+	}
+	if wait, _ := store.Reserve(ctx, "other", 0); wait != 0 {
+		t.Errorf("expected the names not to share their failures, got %v", wait)
+	}
+
+	store.Record(ctx, "api", nil)
+	if wait, _ := store.Reserve(ctx, "api", 0); wait != 0 {
+		t.Errorf("expected a success to reset the failures, got %v", wait)
+	}
+}
+
+func TestWithStoreSharedAcrossInstances(t *testing.T) {
+	store := NewMemoryStore(Constant(time.Minute))
+	failing := Retry(func() error { return errors.New("failed") }).SetRetries(2).WithStore(store, "api")
+	if slept := sleeps(failing); len(slept) != 1 || slept[0] < 59*time.Second {
+		t.Errorf("expected the retry to wait for the recorded failure, got %v", slept)
+	}
+
+	succeeding := Retry(func() error { return nil }).WithStore(store, "api")
+	if slept := sleeps(succeeding); len(slept) != 1 || slept[0] < 59*time.Second {
+		t.Errorf("expected the other instance to wait for the failures, got %v", slept)
+	}
+	if slept := sleeps(succeeding); len(slept) != 0 {
+		t.Errorf("expected no wait once a success reset the failures, got %v", slept)
+	}
+}
+
+// failingStore is a RetryStore whose Reserve fails.
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) Reserve(context.Context, string, int) (time.Duration, error) {
+	return 0, s.err
+}
+
+func (s failingStore) Record(context.Context, string, error) error {
+	return nil
+}
+
+func TestWithStoreReserveError(t *testing.T) {
+	errUnavailable := errors.New("store unavailable")
+	calls := 0
+	stats := Retry(func() error {
+		calls++
+		return nil
+	}).SetRetries(3).WithStore(failingStore{err: errUnavailable}, "api").Exec()
+
+	if !errors.Is(stats.Err, errUnavailable) || stats.Outcome != OutcomeAborted {
+		t.Errorf("expected %v, got %v (%v)", errUnavailable, stats.Err, stats.Outcome)
+	}
+	if calls != 0 {
+		t.Errorf("expected no attempt, got %d", calls)
+	}
+}
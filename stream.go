@@ -0,0 +1,77 @@
+package retryable
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// RetrayableReader retries copying a stream into a writer, resuming every
+// attempt from the last byte copied successfully instead of restarting, for
+// example for large downloads. It embeds RetrayableI, so every setting is
+// available.
+type RetrayableReader struct {
+	RetrayableI
+	mu     sync.Mutex
+	offset atomic.Int64
+}
+
+// The Offset method returns the number of bytes copied to the writer so far,
+// it is the offset the next attempt resumes from. It is safe to call it from
+// another goroutine.
+func (r *RetrayableReader) Offset() int64 {
+	return r.offset.Load()
+}
+
+// copy executes one attempt, the attempts are serialized so a timed out
+// attempt stops writing before the next one resumes.
+func (r *RetrayableReader) copy(ctx context.Context, w io.Writer, open func(ctx context.Context, offset int64) (io.ReadCloser, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rc, err := open(ctx, r.offset.Load())
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := rc.Read(buf)
+		if n > 0 {
+			written, werr := w.Write(buf[:n])
+			r.offset.Add(int64(written))
+			if werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// The function RetryReader is creating and returning an instance of the type
+// RetrayableReader that copies into w the stream returned by open.
+// The open function receives the context of the attempt and the offset to
+// resume from, the number of bytes already copied into w, and must return a
+// reader that starts at that offset, like an HTTP request with a Range
+// header. An attempt fails when open or a read fails and succeeds when the
+// reader returns io.EOF. The offset is kept across Exec calls.
+func RetryReader(ctx context.Context, w io.Writer, open func(ctx context.Context, offset int64) (io.ReadCloser, error)) *RetrayableReader {
+	rr := &RetrayableReader{}
+	rr.RetrayableI = newRetrayable(ctx, func(ctx context.Context, _ int) error {
+		return rr.copy(ctx, w, open)
+	}).requireFunc(open == nil)
+	return rr
+}
@@ -0,0 +1,73 @@
+package retryable
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// failingReader reads at most n bytes of r and then fails with err.
+type failingReader struct {
+	r   io.Reader
+	n   int
+	err error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, f.err
+	}
+	if len(p) > f.n {
+		p = p[:f.n]
+	}
+	n, err := f.r.Read(p)
+	f.n -= n
+	return n, err
+}
+
+func TestRetryReaderResumes(t *testing.T) {
+	const data = "the quick brown fox jumps over the lazy dog"
+	errReset := errors.New("connection reset")
+	var offsets []int64
+	var out bytes.Buffer
+	rr := RetryReader(context.Background(), &out, func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		offsets = append(offsets, offset)
+		var r io.Reader = strings.NewReader(data[offset:])
+		if len(offsets) < 3 {
+			r = &failingReader{r: r, n: 10, err: errReset}
+		}
+		return io.NopCloser(r), nil
+	})
+
+	stats := rr.SetRetries(5).Exec()
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if out.String() != data {
+		t.Errorf("expected %q, got %q", data, out.String())
+	}
+	if len(offsets) != 3 || offsets[0] != 0 || offsets[1] != 10 || offsets[2] != 20 {
+		t.Errorf("expected every attempt to resume from the bytes copied, got %v", offsets)
+	}
+	if rr.Offset() != int64(len(data)) {
+		t.Errorf("expected the offset %d, got %d", len(data), rr.Offset())
+	}
+}
+
+func TestRetryReaderOpenFails(t *testing.T) {
+	errOpen := errors.New("not found")
+	var out bytes.Buffer
+	rr := RetryReader(context.Background(), &out, func(context.Context, int64) (io.ReadCloser, error) {
+		return nil, errOpen
+	})
+
+	if stats := rr.SetRetries(2).Exec(); !errors.Is(stats.Err, errOpen) || stats.Attempts != 2 {
+		t.Errorf("expected %v after 2 attempts, got %v after %d", errOpen, stats.Err, stats.Attempts)
+	}
+	if out.Len() != 0 || rr.Offset() != 0 {
+		t.Errorf("expected nothing copied, got %d bytes", rr.Offset())
+	}
+}
@@ -0,0 +1,96 @@
+package retryable
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// reportRetained reports the heap still retained after the benchmark per
+// operation, like the timers that were not stopped and linger until they
+// fire. Since Go 1.23 the runtime also collects the timers that are not
+// referenced anymore, so time.After only retains them with older toolchains.
+func reportRetained(b *testing.B, run func()) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	run()
+	b.StopTimer()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	retained := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	if retained < 0 {
+		retained = 0
+	}
+	b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+}
+
+func BenchmarkTimerStopped(b *testing.B) {
+	r := Retry(func() error { return nil }).(*Retrayable)
+	reportRetained(b, func() {
+		for i := 0; i < b.N; i++ {
+			_, stop := r.timer(time.Hour)
+			stop()
+		}
+	})
+}
+
+func BenchmarkTimeAfter(b *testing.B) {
+	reportRetained(b, func() {
+		for i := 0; i < b.N; i++ {
+			time.After(time.Hour)
+		}
+	})
+}
+
+func BenchmarkExecWithTimeout(b *testing.B) {
+	rt := Retry(func() error { return nil }).SetTimeout(time.Hour)
+	reportRetained(b, func() {
+		for i := 0; i < b.N; i++ {
+			rt.Exec()
+		}
+	})
+}
+
+func BenchmarkTimerNoTimeout(b *testing.B) {
+	r := Retry(func() error { return nil }).(*Retrayable)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, stop := r.timer(0)
+		stop()
+	}
+}
+
+// BenchmarkNeverFiringChannel is the cost of the never firing channel the
+// timer of a zero timeout used to allocate.
+func BenchmarkNeverFiringChannel(b *testing.B) {
+	b.ReportAllocs()
+	var ch <-chan time.Time
+	for i := 0; i < b.N; i++ {
+		ch = make(chan time.Time)
+	}
+	_ = ch
+}
+
+func BenchmarkExecNoTimeout(b *testing.B) {
+	rt := Retry(func() error { return nil })
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rt.Exec()
+	}
+}
+
+func TestTimerNoTimeoutAllocs(t *testing.T) {
+	r := Retry(func() error { return nil }).(*Retrayable)
+	allocs := testing.AllocsPerRun(100, func() {
+		ch, stop := r.timer(0)
+		stop()
+		if ch != nil {
+			t.Fatal("expected a nil channel")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocation, got %v", allocs)
+	}
+}
@@ -0,0 +1,136 @@
+package retryable
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// The Value field is the value returned by the successful attempt, it is
+// always the zero value of T when the execution fails, even if a timed out
+// attempt finishes successfully later, and when no attempt succeeded but a
+// Fallback or an Escalate recovered the execution.
+// The LastValue field is the most recent non-zero value returned by any
+// attempt, successful or not, so a partial result is available even when the
+// execution fails.
+type ValueStats[T any] struct {
+	Stats
+	Value     T
+	LastValue T
+}
+
+// RetrayableValue retries a function that returns a value together with an
+// error. It embeds RetrayableI, so every setting is available, but the setters
+// return RetrayableI, so keep a reference to the RetrayableValue to call its
+// typed Exec, or use Configure to keep chaining:
+//
+//	rt := retryable.RetryValue(FetchUser)
+//	rt.SetRetries(3).SetSleep(time.Second)
+//
+//	stats := rt.Exec()
+//	fmt.Println(stats.Value)
+//
+//	stats = retryable.RetryValue(FetchUser).
+//		Configure(func(rt retryable.RetrayableI) { rt.SetRetries(3) }).
+//		Exec()
+type RetrayableValue[T any] struct {
+	RetrayableI
+	mu     sync.Mutex
+	run    int
+	values map[int]T
+}
+
+// The function RetryValue is creating and returning an instance of the type
+// RetrayableValue. The function takes an argument fn, which is a function
+// that returns a value and an error. T is inferred from fn, so it never has
+// to be written and Exec returns the value typed, without any cast: a
+// mismatched type is a compile error, not a runtime one.
+func RetryValue[T any](fn func() (T, error)) *RetrayableValue[T] {
+	rv := &RetrayableValue[T]{values: map[int]T{}}
+	rv.RetrayableI = newRetrayable(context.Background(), func(_ context.Context, attempt int) error {
+		rv.mu.Lock()
+		run := rv.run
+		rv.mu.Unlock()
+
+		value, err := fn()
+		rv.mu.Lock()
+		// attempts that outlive their Exec must not leak into the next one
+		if run == rv.run {
+			rv.values[attempt] = value
+		}
+		rv.mu.Unlock()
+		return err
+	}).requireFunc(fn == nil)
+	return rv
+}
+
+// The Configure method calls fn with the RetrayableI of the instance to change
+// its settings and returns the typed instance, so the chain can end with its
+// typed Exec.
+func (r *RetrayableValue[T]) Configure(fn func(rt RetrayableI)) *RetrayableValue[T] {
+	fn(r.RetrayableI)
+	return r
+}
+
+// The Exec method executes the function with the specified settings and returns
+// a ValueStats with the Stats of the execution and the values returned by the
+// function.
+func (r *RetrayableValue[T]) Exec() ValueStats[T] {
+	r.mu.Lock()
+	r.run++
+	r.values = map[int]T{}
+	r.mu.Unlock()
+
+	stats := ValueStats[T]{Stats: r.RetrayableI.Exec()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// a Fallback or an Escalate that recovered the execution returns no value
+	if stats.Err == nil && stats.succeeded > 0 {
+		stats.Value = r.values[stats.succeeded-1]
+	}
+	for attempt := stats.Retries; attempt >= 0; attempt-- {
+		value, ok := r.values[attempt]
+		if ok && !reflect.ValueOf(&value).Elem().IsZero() {
+			stats.LastValue = value
+			break
+		}
+	}
+	return stats
+}
+
+// RetrayableValueE is like RetrayableValue, but its Exec returns the value,
+// the Stats and the error separately:
+//
+//	rt := retryable.RetryValueE(FetchUser)
+//	rt.SetRetries(3).SetSleep(time.Second)
+//
+//	user, stats, err := rt.Exec()
+type RetrayableValueE[T any] struct {
+	*RetrayableValue[T]
+}
+
+// The function RetryValueE is creating and returning an instance of the type
+// RetrayableValueE. The function takes an argument fn, which is a function
+// that returns a value and an error, T is inferred from it like in
+// RetryValue.
+func RetryValueE[T any](fn func() (T, error)) *RetrayableValueE[T] {
+	return &RetrayableValueE[T]{RetryValue(fn)}
+}
+
+// The Configure method calls fn with the RetrayableI of the instance to change
+// its settings and returns the typed instance, like RetrayableValue.Configure.
+func (r *RetrayableValueE[T]) Configure(fn func(rt RetrayableI)) *RetrayableValueE[T] {
+	fn(r.RetrayableI)
+	return r
+}
+
+// The Exec method executes the function with the specified settings and returns
+// the value of the successful attempt, the Stats of the execution and the
+// error result of the function (the same as Stats.Err).
+// When the execution fails the returned value is always the zero value of T,
+// even if a timed out attempt finishes successfully later.
+func (r *RetrayableValueE[T]) Exec() (T, Stats, error) {
+	stats := r.RetrayableValue.Exec()
+	return stats.Value, stats.Stats, stats.Err
+}
@@ -0,0 +1,86 @@
+package retryable
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRetryValueNoValueOnFallback(t *testing.T) {
+	stats := RetryValue(func() (string, error) { return "partial", errors.New("failed") }).
+		Configure(func(rt RetrayableI) {
+			rt.SetRetries(2).Fallback(func(error) error { return nil })
+		}).Exec()
+
+	if stats.Err != nil || !stats.UsedFallback {
+		t.Fatalf("expected a fallback success, got %v", stats.Err)
+	}
+	if stats.Value != "" {
+		t.Errorf("expected no value, got %q", stats.Value)
+	}
+	if stats.LastValue != "partial" {
+		t.Errorf("expected the last value %q, got %q", "partial", stats.LastValue)
+	}
+}
+
+func TestRetryValueNoValueOnEscalate(t *testing.T) {
+	stats := RetryValue(func() (int, error) { return 42, errors.New("failed") }).
+		Configure(func(rt RetrayableI) {
+			rt.SetRetries(2).Escalate(func(error) error { return nil })
+		}).Exec()
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if stats.Value != 0 {
+		t.Errorf("expected no value, got %d", stats.Value)
+	}
+}
+
+func TestRetryValueOfTheSuccessfulAttempt(t *testing.T) {
+	calls := 0
+	stats := RetryValue(func() (int, error) {
+		calls++
+		if calls < 3 {
+			return calls, errors.New("failed")
+		}
+		return calls, nil
+	}).Configure(func(rt RetrayableI) { rt.SetRetries(5) }).Exec()
+
+	if stats.Err != nil {
+		t.Fatalf("unexpected error: %v", stats.Err)
+	}
+	if stats.Value != 3 {
+		t.Errorf("expected 3, got %d", stats.Value)
+	}
+}
+
+func TestRetryValueE(t *testing.T) {
+	calls := 0
+	value, stats, err := RetryValueE(func() (string, error) {
+		calls++
+		if calls < 2 {
+			return "", errors.New("failed")
+		}
+		return "ok", nil
+	}).Configure(func(rt RetrayableI) { rt.SetRetries(3) }).Exec()
+
+	if err != nil || err != stats.Err {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "ok" || stats.Attempts != 2 {
+		t.Errorf("expected %q after 2 attempts, got %q after %d", "ok", value, stats.Attempts)
+	}
+}
+
+func TestRetryValueEFailure(t *testing.T) {
+	errFailed := errors.New("failed")
+	value, stats, err := RetryValueE(func() (int, error) { return 7, errFailed }).
+		Configure(func(rt RetrayableI) { rt.SetRetries(2) }).Exec()
+
+	if !errors.Is(err, errFailed) || stats.Outcome != OutcomeExhausted {
+		t.Errorf("expected %v, got %v (%v)", errFailed, err, stats.Outcome)
+	}
+	if value != 0 {
+		t.Errorf("expected the zero value, got %d", value)
+	}
+}
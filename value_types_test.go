@@ -0,0 +1,51 @@
+package retryable
+
+import (
+	"errors"
+	"testing"
+)
+
+// The tests of this file check the types at compile time: they do not build
+// if Exec stops returning the type of the function, without any cast.
+
+type user struct {
+	name string
+}
+
+func fetchUser() (*user, error) {
+	return &user{name: "gopher"}, nil
+}
+
+func TestRetryValueTypes(t *testing.T) {
+	var rv *RetrayableValue[*user] = RetryValue(fetchUser)
+	var stats ValueStats[*user] = rv.Exec()
+	var value *user = stats.Value
+	var last *user = stats.LastValue
+
+	if value == nil || value.name != "gopher" || last != value {
+		t.Errorf("expected the user, got %v and %v", value, last)
+	}
+}
+
+func TestRetryValueConfigureTypes(t *testing.T) {
+	var rv *RetrayableValue[[]int] = RetryValue(func() ([]int, error) { return []int{1, 2}, nil }).
+		Configure(func(rt RetrayableI) { rt.SetRetries(2) })
+	var values []int = rv.Exec().Value
+
+	if len(values) != 2 {
+		t.Errorf("expected 2 values, got %v", values)
+	}
+}
+
+func TestRetryValueETypes(t *testing.T) {
+	var rv *RetrayableValueE[string] = RetryValueE(func() (string, error) { return "", errors.New("failed") }).
+		Configure(func(rt RetrayableI) { rt.SetRetries(2) })
+	var value string
+	var stats Stats
+	var err error
+	value, stats, err = rv.Exec()
+
+	if value != "" || err == nil || err != stats.Err || stats.Attempts != 2 {
+		t.Errorf("expected a failure after 2 attempts, got %q, %v and %d attempts", value, err, stats.Attempts)
+	}
+}